@@ -0,0 +1,191 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+)
+
+// Preferences represents the account preferences of the logged-in user.
+// Every field is a pointer so that a Preferences holding only the fields to
+// change can be passed to UpdatePrefs; nil fields are left untouched.
+type Preferences struct {
+	AcceptPMs              *string `json:"accept_pms,omitempty"`
+	AllowClickTracking     *bool   `json:"allow_clicktracking,omitempty"`
+	Beta                   *bool   `json:"beta,omitempty"`
+	ClickGadget            *bool   `json:"clickgadget,omitempty"`
+	CollapseReadMessages   *bool   `json:"collapse_read_messages,omitempty"`
+	Compress               *bool   `json:"compress,omitempty"`
+	CountryCode            *string `json:"country_code,omitempty"`
+	DefaultCommentSort     *string `json:"default_comment_sort,omitempty"`
+	DomainDetails          *bool   `json:"domain_details,omitempty"`
+	EmailDigests           *bool   `json:"email_digests,omitempty"`
+	EmailMessages          *bool   `json:"email_messages,omitempty"`
+	EmailUnsubscribeAll    *bool   `json:"email_unsubscribe_all,omitempty"`
+	EnableDefaultThemes    *bool   `json:"enable_default_themes,omitempty"`
+	HideAds                *bool   `json:"hide_ads,omitempty"`
+	HideDowns              *bool   `json:"hide_downs,omitempty"`
+	HideFromRobots         *bool   `json:"hide_from_robots,omitempty"`
+	HideUps                *bool   `json:"hide_ups,omitempty"`
+	HighlightControversial *bool   `json:"highlight_controversial,omitempty"`
+	HighlightNewComments   *bool   `json:"highlight_new_comments,omitempty"`
+	IgnoreSuggestedSort    *bool   `json:"ignore_suggested_sort,omitempty"`
+	LabelNSFW              *bool   `json:"label_nsfw,omitempty"`
+	Lang                   *string `json:"lang,omitempty"`
+	MarkMessagesRead       *bool   `json:"mark_messages_read,omitempty"`
+	Media                  *string `json:"media,omitempty"`
+	MediaPreview           *string `json:"media_preview,omitempty"`
+	MinCommentScore        *int    `json:"min_comment_score,omitempty"`
+	MinLinkScore           *int    `json:"min_link_score,omitempty"`
+	NewWindow              *bool   `json:"newwindow,omitempty"`
+	NightMode              *bool   `json:"nightmode,omitempty"`
+	NoProfanity            *bool   `json:"no_profanity,omitempty"`
+	NumComments            *int    `json:"num_comments,omitempty"`
+	NumSites               *int    `json:"numsites,omitempty"`
+	Over18                 *bool   `json:"over_18,omitempty"`
+	PrivateFeeds           *bool   `json:"private_feeds,omitempty"`
+	ProfileOptOut          *bool   `json:"profile_opt_out,omitempty"`
+	PublicVotes            *bool   `json:"public_votes,omitempty"`
+	Research               *bool   `json:"research,omitempty"`
+	SearchIncludeOver18    *bool   `json:"search_include_over_18,omitempty"`
+	ShowFlair              *bool   `json:"show_flair,omitempty"`
+	ShowLinkFlair          *bool   `json:"show_link_flair,omitempty"`
+	ShowPresence           *bool   `json:"show_presence,omitempty"`
+	ShowStylesheets        *bool   `json:"show_stylesheets,omitempty"`
+	ShowTrending           *bool   `json:"show_trending,omitempty"`
+	StoreVisits            *bool   `json:"store_visits,omitempty"`
+	ThemeSelector          *string `json:"theme_selector,omitempty"`
+	ThreadedMessages       *bool   `json:"threaded_messages,omitempty"`
+	ThreadedModmail        *bool   `json:"threaded_modmail,omitempty"`
+	TopKarmaSubreddits     *bool   `json:"top_karma_subreddits,omitempty"`
+	UseGlobalDefaults      *bool   `json:"use_global_defaults,omitempty"`
+	VideoAutoplay          *bool   `json:"video_autoplay,omitempty"`
+	ActivityRelevantAds    *bool   `json:"activity_relevant_ads,omitempty"`
+	ThirdPartyDataPersonal *bool   `json:"third_party_data_personalized_ads,omitempty"`
+	ThirdPartySitePersonal *bool   `json:"third_party_site_data_personalized_ads,omitempty"`
+	ThirdPartySiteContent  *bool   `json:"third_party_site_data_personalized_content,omitempty"`
+	FeedRecommendations    *bool   `json:"feed_recommendations_enabled,omitempty"`
+	LegacySearch           *bool   `json:"legacy_search,omitempty"`
+	InRedesignBeta         *bool   `json:"in_redesign_beta,omitempty"`
+	SendWelcomeMessages    *bool   `json:"send_welcome_messages,omitempty"`
+	SendCrosspostMessages  *bool   `json:"send_crosspost_messages,omitempty"`
+	ShowTwitter            *bool   `json:"show_twitter,omitempty"`
+	SurveyLastSeenTime     *int64  `json:"survey_last_seen_time,omitempty"`
+	EnableFollowers        *bool   `json:"enable_followers,omitempty"`
+}
+
+// Prefs returns the preferences of the logged-in user.
+func (s *OAuthSession) Prefs() (*Preferences, error) {
+	if err := s.requireScope(IdentityScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/api/v1/me/prefs")
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := &Preferences{}
+	if err = json.NewDecoder(body).Decode(prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// UpdatePrefs changes the preferences set in prefs, leaving the nil ones
+// untouched, and returns the resulting preferences.
+func (s *OAuthSession) UpdatePrefs(prefs *Preferences) (*Preferences, error) {
+	if err := s.requireScope(AccountScope); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.Patch(&url.Values{
+		"json": {string(b)},
+	}, "/api/v1/me/prefs")
+	if err != nil {
+		return nil, err
+	}
+
+	updated := &Preferences{}
+	if err = json.NewDecoder(body).Decode(updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Karma returns the per-subreddit karma breakdown of the logged-in user.
+func (s *OAuthSession) Karma() ([]*SubredditKarma, error) {
+	if err := s.requireScope(MySubredditsScope); err != nil {
+		return nil, err
+	}
+	return karma(s)
+}
+
+// MyTrophies returns the trophies of the logged-in user.
+func (s *OAuthSession) MyTrophies() ([]*Trophy, error) {
+	if err := s.requireScope(IdentityScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/api/v1/me/trophies")
+	if err != nil {
+		return nil, err
+	}
+	return decodeTrophies(body)
+}
+
+// UserTrophies returns the trophies of the given user.
+func (s *OAuthSession) UserTrophies(username string) ([]*Trophy, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/api/v1/user/%s/trophies", username)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTrophies(body)
+}
+
+// Friends returns the friends of the logged-in user.
+func (s *OAuthSession) Friends() ([]*Relationship, error) {
+	if err := s.requireScope(MySubredditsScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/api/v1/me/friends")
+	if err != nil {
+		return nil, err
+	}
+	return decodeUserList(body)
+}
+
+// Blocked returns the users blocked by the logged-in user.
+func (s *OAuthSession) Blocked() ([]*Relationship, error) {
+	if err := s.requireScope(MySubredditsScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/prefs/blocked")
+	if err != nil {
+		return nil, err
+	}
+	return decodeUserList(body)
+}
+
+// decodeUserList decodes an unpaginated UserList.
+func decodeUserList(body io.Reader) ([]*Relationship, error) {
+	type Response struct {
+		Data struct {
+			Children []*Relationship `json:"children"`
+		} `json:"data"`
+	}
+	r := &Response{}
+	if err := json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+	return r.Data.Children, nil
+}
@@ -0,0 +1,105 @@
+package geddit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestPrefsRoundTrip(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefs, err := s.Prefs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefs.Lang == nil || *prefs.Lang != "en" || prefs.NightMode == nil || *prefs.NightMode {
+		t.Fatalf("got prefs %+v", prefs)
+	}
+	if prefs.MinCommentScore == nil || *prefs.MinCommentScore != -4 {
+		t.Errorf("got min_comment_score %v", prefs.MinCommentScore)
+	}
+
+	nightMode, lang := true, "de"
+	updated, err := s.UpdatePrefs(&Preferences{NightMode: &nightMode, Lang: &lang})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updated.NightMode != true || *updated.Lang != "de" {
+		t.Errorf("got updated prefs %+v", updated)
+	}
+	// Preferences left nil are untouched.
+	if updated.Over18 == nil || *updated.Over18 || updated.ShowFlair == nil || !*updated.ShowFlair {
+		t.Errorf("got updated prefs %+v", updated)
+	}
+	prefs, err = s.Prefs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prefs, updated) {
+		t.Errorf("got prefs %+v, want %+v", prefs, updated)
+	}
+}
+
+func TestAccountLists(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	karma, err := s.Karma()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// gopher submitted l1 (50) and wrote c2 and c3 (1 each) in golang.
+	if want := []*SubredditKarma{{Subreddit: "golang", LinkKarma: 50, CommentKarma: 2}}; !reflect.DeepEqual(karma, want) {
+		t.Errorf("got karma %+v, want %+v", karma, want)
+	}
+
+	trophies, err := s.MyTrophies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trophies) != 1 || trophies[0].Name != "Verified Email" || trophies[0].ID != nil {
+		t.Errorf("got trophies %+v", trophies)
+	}
+	if trophies, err = s.UserTrophies(geddittest.Moderator); err != nil || len(trophies) != 0 {
+		t.Errorf("got %v, %v", trophies, err)
+	}
+
+	mod, err := s.AboutRedditor(geddittest.Moderator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Friend("", geddittest.Moderator, FriendRelationship, FriendOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	friends, err := s.Friends()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(friends) != 1 || friends[0].Name != geddittest.Moderator || friends[0].Added().IsZero() {
+		t.Errorf("got friends %+v", friends)
+	}
+
+	if err := mod.Block(); err != nil {
+		t.Fatal(err)
+	}
+	blocked, err := s.Blocked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocked) != 1 || blocked[0].Name != geddittest.Moderator {
+		t.Errorf("got blocked %+v", blocked)
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a cached response.
+type CacheEntry struct {
+	Header       http.Header
+	Body         []byte
+	Expires      time.Time
+	ETag         string
+	LastModified string
+}
+
+// Cache stores responses for a session, see SetCache. Implementations must
+// be safe for concurrent use.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, e *CacheEntry)
+	Delete(key string)
+}
+
+// CacheOptions controls which responses a session caches and for how long.
+type CacheOptions struct {
+	// DefaultTTL is how long responses are fresh when no TTLs pattern
+	// matches their path.
+	DefaultTTL time.Duration
+	// TTLs are the TTLs of the responses whose URL path matches their
+	// pattern. The first matching one applies, so more specific patterns
+	// go first, e.g.
+	//
+	//	[]CacheTTL{
+	//		{"/r/golang/about", time.Minute},
+	//		{"/r/*/about", time.Hour},      // OAuthSession
+	//		{"/r/*/about.json", time.Hour}, // Session and LoginSession
+	//	}
+	TTLs []CacheTTL
+}
+
+// CacheTTL is the TTL of the responses whose URL path matches Pattern, a
+// path.Match pattern.
+type CacheTTL struct {
+	Pattern string
+	TTL     time.Duration
+}
+
+// ttl returns how long a response for urlpath stays fresh.
+func (o CacheOptions) ttl(urlpath string) time.Duration {
+	for _, t := range o.TTLs {
+		if ok, _ := path.Match(t.Pattern, urlpath); ok {
+			return t.TTL
+		}
+	}
+	return o.DefaultTTL
+}
+
+// responseCache serves GET requests from a Cache.
+type responseCache struct {
+	cache Cache
+	opts  CacheOptions
+}
+
+// SetCache makes the session serve GET requests from c while they are
+// fresh. Stale responses that had an ETag or Last-Modified header are
+// revalidated with a conditional request. Other methods are never cached.
+// Entries are keyed by method, URL and the user the session is logged in
+// as, if any, so sessions of different users can share a Cache, and
+// sessions of the same user share their entries. Passing nil disables
+// caching.
+func (s *Session) SetCache(c Cache, opts CacheOptions) {
+	s.cache = newResponseCache(c, opts)
+}
+
+// SetCache makes the session serve GET requests from c, see
+// Session.SetCache. Entries are keyed by username rather than access
+// token, so they outlive token renewals and are shared by the sessions of
+// the same account.
+func (s *OAuthSession) SetCache(c Cache, opts CacheOptions) {
+	s.cache = newResponseCache(c, opts)
+}
+
+func newResponseCache(c Cache, opts CacheOptions) *responseCache {
+	if c == nil {
+		return nil
+	}
+	return &responseCache{cache: c, opts: opts}
+}
+
+// wrap returns next with the cache in front of it, keying entries under
+// identity, empty for anonymous requests. A nil cache returns next itself.
+func (c *responseCache) wrap(next Doer, identity string) Doer {
+	if c == nil {
+		return next
+	}
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != "GET" {
+			return next.Do(req)
+		}
+		key := cacheKey(req, identity)
+		e, ok := c.cache.Get(key)
+		if ok && time.Now().Before(e.Expires) {
+			return e.response(req), nil
+		}
+		if ok {
+			if e.ETag != "" {
+				req.Header.Set("If-None-Match", e.ETag)
+			}
+			if e.LastModified != "" {
+				req.Header.Set("If-Modified-Since", e.LastModified)
+			}
+		}
+
+		resp, err := next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		ttl := c.opts.ttl(req.URL.Path)
+		switch {
+		case ok && resp.StatusCode == http.StatusNotModified:
+			resp.Body.Close()
+			e.Expires = time.Now().Add(ttl)
+			c.cache.Set(key, e)
+			return e.response(req), nil
+		case resp.StatusCode != http.StatusOK:
+			return resp, nil
+		}
+
+		e = &CacheEntry{
+			Header:       resp.Header,
+			Expires:      time.Now().Add(ttl),
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+		if ttl <= 0 && e.ETag == "" && e.LastModified == "" {
+			return resp, nil
+		}
+		e.Body, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		c.cache.Set(key, e)
+		resp.Body = ioutil.NopCloser(bytes.NewReader(e.Body))
+		return resp, nil
+	})
+}
+
+// response builds a response out of a cache entry.
+func (e *CacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheKey identifies a request by method, URL and the identity it is made
+// under.
+func cacheKey(req *http.Request, identity string) string {
+	return req.Method + " " + req.URL.String() + " " + identity
+}
+
+// MemoryCache is an in-memory Cache evicting the least recently used
+// entries.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCache creates a MemoryCache holding at most maxEntries entries.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get implements Cache.
+func (c *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	e := *el.Value.(*memoryItem).entry
+	return &e, true
+}
+
+// Set implements Cache.
+func (c *MemoryCache) Set(key string, e *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*memoryItem).entry = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&memoryItem{key: key, entry: e})
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*memoryItem).key)
+	}
+}
+
+// Delete implements Cache.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// FileCache is a Cache storing each entry as a JSON file in a directory.
+// Expired entries are kept for revalidation until overwritten or deleted.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache creates a FileCache in dir, creating the directory if needed.
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+func (c *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements Cache.
+func (c *FileCache) Get(key string) (*CacheEntry, bool) {
+	b, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	e := &CacheEntry{}
+	if json.Unmarshal(b, e) != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+// Set implements Cache. Entries are written to a temporary file first so
+// concurrent readers never see a partial entry.
+func (c *FileCache) Set(key string, e *CacheEntry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	writeFileAtomic(c.path(key), b)
+}
+
+// Delete implements Cache.
+func (c *FileCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// writeFileAtomic writes b to a temporary file next to name, then renames
+// it over name.
+func writeFileAtomic(name string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
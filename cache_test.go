@@ -0,0 +1,292 @@
+package geddit
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// statusRecorder records the status of the responses going through it, and
+// whether their request was conditional.
+type statusRecorder struct {
+	mu          sync.Mutex
+	statuses    []int
+	conditional int
+}
+
+func (r *statusRecorder) middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.Do(req)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if req.Header.Get("If-None-Match") != "" {
+			r.conditional++
+		}
+		if err == nil {
+			r.statuses = append(r.statuses, resp.StatusCode)
+		}
+		return resp, err
+	})
+}
+
+func (r *statusRecorder) sent() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.statuses...)
+}
+
+func newCachedSession(srv *geddittest.Server, c Cache, opts CacheOptions) (*Session, *statusRecorder) {
+	rec := &statusRecorder{}
+	s := NewSession("geddit tests")
+	s.SetHTTPClient(srv.Client())
+	s.Use(rec.middleware)
+	s.SetCache(c, opts)
+	return s, rec
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", &CacheEntry{Body: []byte("a")})
+	c.Set("b", &CacheEntry{Body: []byte("b")})
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	c.Set("c", &CacheEntry{Body: []byte("c")})
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b, the least recently used entry, was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("got %d entries, want 2", c.Len())
+	}
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok || c.Len() != 1 {
+		t.Error("a was not deleted")
+	}
+}
+
+func TestCacheServesFreshResponses(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, rec := newCachedSession(srv, NewMemoryCache(10), CacheOptions{
+		TTLs: []CacheTTL{{"/r/*/about.json", time.Hour}},
+	})
+
+	for i := 0; i < 3; i++ {
+		sr, err := s.AboutSubreddit("golang")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sr.Name != "golang" {
+			t.Fatalf("got subreddit %q", sr.Name)
+		}
+	}
+	if got := rec.sent(); len(got) != 1 {
+		t.Errorf("got %d requests for a fresh entry, want 1", len(got))
+	}
+
+	// Paths without a TTL, and non-GET requests, are not cached.
+	for i := 0; i < 2; i++ {
+		if _, err := s.Comments(&Submission{ID: "l1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := rec.sent(); len(got) != 3 {
+		t.Errorf("got %d requests in all, want 3", len(got))
+	}
+}
+
+func TestCacheOptionsTTL(t *testing.T) {
+	opts := CacheOptions{
+		DefaultTTL: time.Second,
+		TTLs: []CacheTTL{
+			{"/r/golang/about", time.Minute},
+			{"/r/*/about", time.Hour},
+			{"/r/*/*", 2 * time.Hour},
+		},
+	}
+	tests := []struct {
+		path string
+		want time.Duration
+	}{
+		{"/r/golang/about", time.Minute},
+		{"/r/rust/about", time.Hour},
+		{"/r/rust/hot", 2 * time.Hour},
+		{"/user/gopher/about", time.Second},
+	}
+	// Every overlapping pattern matches /r/golang/about, so a random pick
+	// would show over a few calls.
+	for i := 0; i < 20; i++ {
+		for _, tt := range tests {
+			if got := opts.ttl(tt.path); got != tt.want {
+				t.Fatalf("ttl(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestCacheServesOAuthPaths(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	rec := &statusRecorder{}
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Use(rec.middleware)
+	s.SetCache(NewMemoryCache(10), CacheOptions{TTLs: []CacheTTL{{"/r/*/about", time.Hour}}})
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.AboutSubreddit("golang"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := rec.sent(); len(got) != 1 {
+		t.Errorf("got %d requests for a fresh entry, want 1", len(got))
+	}
+}
+
+func TestCacheRevalidatesStaleResponses(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, rec := newCachedSession(srv, NewMemoryCache(10), CacheOptions{DefaultTTL: time.Millisecond})
+
+	first, err := s.AboutSubreddit("golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	second, err := s.AboutSubreddit("golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("revalidated response decoded as %+v, want %+v", second, first)
+	}
+	want := []int{http.StatusOK, http.StatusNotModified}
+	if got := rec.sent(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got statuses %v, want %v", got, want)
+	}
+	if rec.conditional != 1 {
+		t.Errorf("got %d conditional requests, want 1", rec.conditional)
+	}
+}
+
+func TestCacheSharedByAccountAcrossTokens(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	cache := NewMemoryCache(10)
+	opts := CacheOptions{DefaultTTL: time.Hour}
+
+	newSession := func(username string) (*OAuthSession, *statusRecorder) {
+		rec := &statusRecorder{}
+		s, err := NewOAuthSession(username, geddittest.Password, "geddit tests",
+			geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Use(rec.middleware)
+		s.SetCache(cache, opts)
+		return s, rec
+	}
+
+	a, recA := newSession(geddittest.Username)
+	if _, err := a.AboutSubreddit("golang"); err != nil {
+		t.Fatal(err)
+	}
+	// Another worker of the same account, with its own token.
+	b, recB := newSession(geddittest.Username)
+	if _, err := b.AboutSubreddit("golang"); err != nil {
+		t.Fatal(err)
+	}
+	// The same session after renewing its token.
+	srv.ExpireTokens()
+	if _, err := a.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AboutSubreddit("golang"); err != nil {
+		t.Fatal(err)
+	}
+	// Another account.
+	c, recC := newSession(geddittest.Moderator)
+	if _, err := c.AboutSubreddit("golang"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(recB.sent()); n != 0 {
+		t.Errorf("second session of the account sent %d requests, want 0", n)
+	}
+	// The first AboutSubreddit, then Me rejected, the token request and Me
+	// again.
+	if n := len(recA.sent()); n != 4 {
+		t.Errorf("first session sent %d requests, want 4", n)
+	}
+	if n := len(recC.sent()); n != 1 {
+		t.Errorf("session of another account sent %d requests, want 1", n)
+	}
+}
+
+func TestFileCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewFileCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &CacheEntry{
+		Header:       http.Header{"Content-Type": {"application/json"}},
+		Body:         []byte(`{"kind":"t5"}`),
+		Expires:      time.Now().Add(time.Hour).Round(0).UTC(),
+		ETag:         `"abc"`,
+		LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
+	}
+	c.Set("GET https://www.reddit.com/r/golang/about.json", want)
+
+	// A new FileCache on the same directory, as another process would use.
+	c, err = NewFileCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.Get("GET https://www.reddit.com/r/golang/about.json")
+	if !ok {
+		t.Fatal("entry is missing")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got entry %+v, want %+v", got, want)
+	}
+	if _, ok := c.Get("GET https://www.reddit.com/r/rust/about.json"); ok {
+		t.Error("got an entry for another key")
+	}
+	c.Delete("GET https://www.reddit.com/r/golang/about.json")
+	if _, ok := c.Get("GET https://www.reddit.com/r/golang/about.json"); ok {
+		t.Error("entry was not deleted")
+	}
+}
+
+func TestFileCacheServesSession(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	c, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, rec := newCachedSession(srv, c, CacheOptions{DefaultTTL: time.Hour})
+	for i := 0; i < 2; i++ {
+		if _, err := s.AboutSubreddit("golang"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(rec.sent()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"bytes"
+	"sync"
+)
+
+// flightGroup deduplicates concurrent identical GET requests: while one is
+// in flight, callers asking for the same URL wait for it and share its
+// response body instead of sending their own request.
+//
+// Only the raw body is shared, not the value decoded from it, by design:
+// every caller decodes its own copy. This saves the round trip and the rate limit budget
+// but not the decoding, and callers never see each other's changes to the
+// things they get back.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg   sync.WaitGroup
+	body []byte
+	err  error
+}
+
+// do calls fn unless a call for key is already in flight, in which case it
+// waits for that call and returns its result. Every caller gets its own
+// buffer, so each one can consume it.
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) (*bytes.Buffer, error) {
+	if g == nil {
+		body, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewBuffer(body), nil
+	}
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.result()
+	}
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.body, c.err = fn()
+	c.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return c.result()
+}
+
+func (c *flightCall) result() (*bytes.Buffer, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return bytes.NewBuffer(append([]byte(nil), c.body...)), nil
+}
+
+// SetCoalescing makes concurrent identical GET requests of the session
+// share a single HTTP request and its response body, which is off by
+// default. Decoded results are deliberately not shared: each caller
+// decodes the body into its own values, so no caller sees another one's
+// changes to them, and coalescing spares requests rather than CPU. Copies
+// of the session taken afterwards share the same in-flight requests.
+func (s *Session) SetCoalescing(enabled bool) {
+	s.flights = nil
+	if enabled {
+		s.flights = &flightGroup{}
+	}
+}
+
+// SetCoalescing makes concurrent identical GET requests of the session
+// share a single HTTP request and its response body, which is off by
+// default. See Session.SetCoalescing.
+func (s *OAuthSession) SetCoalescing(enabled bool) {
+	s.flights = nil
+	if enabled {
+		s.flights = &flightGroup{}
+	}
+}
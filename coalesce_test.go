@@ -0,0 +1,134 @@
+package geddit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serverDoer sends every request to srv instead of reddit.
+func serverDoer(srv *httptest.Server) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme = "http"
+		req.URL.Host = srv.Listener.Addr().String()
+		return srv.Client().Do(req)
+	})
+}
+
+// aboutServer serves /r/golang/about.json once release is closed and
+// counts the requests it receives.
+func aboutServer(hits *int32, release chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		<-release
+		if r.URL.Path != "/r/golang/about.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"kind": "t5", "data": {"display_name": "golang"}}`))
+	}))
+}
+
+func aboutConcurrently(s *Session, n int, sub string) ([]*Subreddit, []error) {
+	subs := make([]*Subreddit, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			subs[i], errs[i] = s.AboutSubreddit(sub)
+		}(i)
+	}
+	wg.Wait()
+	return subs, errs
+}
+
+func TestCoalescing(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	srv := aboutServer(&hits, release)
+	defer srv.Close()
+
+	s := NewSession("geddit tests")
+	s.SetHTTPClient(serverDoer(srv))
+	s.SetCoalescing(true)
+
+	go func() {
+		// Give every goroutine time to join the request in flight.
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+	subs, errs := aboutConcurrently(s, 20, "golang")
+
+	for i := range subs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if subs[i].Name != "golang" {
+			t.Errorf("got subreddit %q, want golang", subs[i].Name)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+
+	// Requests made after the first completed are not coalesced with it.
+	if _, err := s.AboutSubreddit("golang"); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server got %d requests, want 2", got)
+	}
+}
+
+func TestCoalescingSharesErrors(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	srv := aboutServer(&hits, release)
+	defer srv.Close()
+
+	s := NewSession("geddit tests")
+	s.SetHTTPClient(serverDoer(srv))
+	s.SetCoalescing(true)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+	_, errs := aboutConcurrently(s, 10, "missing")
+
+	for _, err := range errs {
+		serr, ok := err.(*StatusError)
+		if !ok || serr.StatusCode != http.StatusNotFound {
+			t.Errorf("got error %v, want a 404 StatusError", err)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+}
+
+func TestCoalescingDisabled(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	close(release)
+	srv := aboutServer(&hits, release)
+	defer srv.Close()
+
+	s := NewSession("geddit tests")
+	s.SetHTTPClient(serverDoer(srv))
+
+	_, errs := aboutConcurrently(s, 10, "golang")
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 10 {
+		t.Errorf("server got %d requests, want 10", got)
+	}
+}
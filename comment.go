@@ -5,7 +5,9 @@
 package geddit
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // Comment represents a reddit comment.
@@ -28,66 +30,148 @@ type Comment struct {
 	AuthorFlairCSSClass *string //`json:"author_flair_css_class"`
 	NumReports          *int    //`json:"num_reports"`
 	Likes               *int    //`json:"likes"`
+	Permalink           string  //`json:"permalink"`
 	Replies             []*Comment
+
+	// web is the WWW host of the session the comment was fetched with.
+	web string
 }
 
 func (c Comment) voteID() string   { return c.FullID }
 func (c Comment) deleteID() string { return c.FullID }
 func (c Comment) replyID() string  { return c.FullID }
 
+// FullPermalink returns the full URL of a comment, on the WWW host of the
+// session it was fetched with.
+func (c *Comment) FullPermalink() string {
+	path := c.Permalink
+	if path == "" {
+		path = fmt.Sprintf("/comments/%s/_/%s/",
+			strings.TrimPrefix(c.LinkID, "t3_"), strings.TrimPrefix(c.FullID, "t1_"))
+	}
+	return webURL(c.web, path)
+}
+
 func (c Comment) String() string {
 	return fmt.Sprintf("%s (%d/%d): %s", c.Author, c.UpVotes, c.DownVotes, c.Body)
 }
 
-// makeComment tries its best to fill as many fields as possible of a Comment.
-func makeComment(cmap map[string]interface{}) *Comment {
-	ret := new(Comment)
-	ret.Author, _ = cmap["author"].(string)
-	ret.Body, _ = cmap["body"].(string)
-	ret.BodyHTML, _ = cmap["body_html"].(string)
-	ret.Subreddit, _ = cmap["subreddit"].(string)
-	ret.LinkID, _ = cmap["link_id"].(string)
-	ret.ParentID, _ = cmap["parent_id"].(string)
-	ret.SubredditID, _ = cmap["subreddit_id"].(string)
-	ret.FullID, _ = cmap["name"].(string)
-	ret.UpVotes, _ = cmap["ups"].(float64)
-	ret.DownVotes, _ = cmap["downs"].(float64)
-	ret.Created, _ = cmap["created_utc"].(float64)
-	ret.Edited, _ = cmap["edited"].(bool)
-	ret.BannedBy, _ = cmap["banned_by"].(*string)
-	ret.ApprovedBy, _ = cmap["approved_by"].(*string)
-	ret.AuthorFlairTxt, _ = cmap["author_flair_text"].(*string)
-	ret.AuthorFlairCSSClass, _ = cmap["author_flair_css_class"].(*string)
-	ret.NumReports, _ = cmap["num_reports"].(*int)
-	ret.Likes, _ = cmap["likes"].(*int)
-
-	helper := new(helper)
-	helper.buildComments(cmap["replies"])
-	ret.Replies = helper.comments
-
-	return ret
+// commentData is the JSON form of a comment, decoded into a Comment.
+type commentData struct {
+	Author              string          `json:"author"`
+	Body                string          `json:"body"`
+	BodyHTML            string          `json:"body_html"`
+	Subreddit           string          `json:"subreddit"`
+	LinkID              string          `json:"link_id"`
+	ParentID            string          `json:"parent_id"`
+	SubredditID         string          `json:"subreddit_id"`
+	FullID              string          `json:"name"`
+	UpVotes             float64         `json:"ups"`
+	DownVotes           float64         `json:"downs"`
+	Created             float64         `json:"created_utc"`
+	Edited              edited          `json:"edited"`
+	BannedBy            *string         `json:"banned_by"`
+	ApprovedBy          *string         `json:"approved_by"`
+	AuthorFlairTxt      *string         `json:"author_flair_text"`
+	AuthorFlairCSSClass *string         `json:"author_flair_css_class"`
+	NumReports          *int            `json:"num_reports"`
+	Likes               *bool           `json:"likes"`
+	Permalink           string          `json:"permalink"`
+	Replies             *commentListing `json:"replies"`
 }
 
-//Helper struct to keep our interesting stuff
-type helper struct {
-	comments []*Comment
+// comment returns the comment and its replies, linking to the WWW host web.
+func (d *commentData) comment(web string) *Comment {
+	c := &Comment{
+		Author:              d.Author,
+		Body:                d.Body,
+		BodyHTML:            d.BodyHTML,
+		Subreddit:           d.Subreddit,
+		LinkID:              d.LinkID,
+		ParentID:            d.ParentID,
+		SubredditID:         d.SubredditID,
+		FullID:              d.FullID,
+		UpVotes:             d.UpVotes,
+		DownVotes:           d.DownVotes,
+		Created:             d.Created,
+		Edited:              bool(d.Edited),
+		BannedBy:            d.BannedBy,
+		ApprovedBy:          d.ApprovedBy,
+		AuthorFlairTxt:      d.AuthorFlairTxt,
+		AuthorFlairCSSClass: d.AuthorFlairCSSClass,
+		NumReports:          d.NumReports,
+		Permalink:           d.Permalink,
+		Replies:             d.Replies.comments(web),
+		web:                 web,
+	}
+	if d.Likes != nil {
+		// Same values as the vote directions.
+		likes := -1
+		if *d.Likes {
+			likes = 1
+		}
+		c.Likes = &likes
+	}
+	return c
 }
 
-//Recursive function to find the fields we want and build the Comments
-//Way too hackish for my likes
-func (h *helper) buildComments(inf interface{}) {
-	switch tp := inf.(type) {
-	case []interface{}: //Maybe array for base comments
-		for _, k := range tp {
-			h.buildComments(k)
-		}
-	case map[string]interface{}: //Maybe comment data
-		if tp["body"] == nil {
-			for _, k := range tp {
-				h.buildComments(k)
-			}
-		} else {
-			h.comments = append(h.comments, makeComment(tp))
+// edited decodes the edited field of a comment, which is false or the time
+// of the last edit.
+type edited bool
+
+func (e *edited) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	*e = s != "false" && s != "null"
+	return nil
+}
+
+// commentListing is the JSON form of a listing of comments, replies
+// included. The whole tree is decoded with these types, rather than into
+// interface{} values walked afterwards.
+type commentListing struct {
+	Data struct {
+		Children []struct {
+			Kind string      `json:"kind"`
+			Data commentData `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// comments returns the comments of the listing, skipping "more" stubs and
+// other kinds of things.
+func (l *commentListing) comments(web string) []*Comment {
+	if l == nil || len(l.Data.Children) == 0 {
+		return nil
+	}
+	comments := make([]*Comment, 0, len(l.Data.Children))
+	for i := range l.Data.Children {
+		if child := &l.Data.Children[i]; child.Kind == "t1" {
+			comments = append(comments, child.Data.comment(web))
 		}
 	}
+	return comments
+}
+
+// UnmarshalJSON decodes a listing, or the empty string reddit sends instead
+// of the replies of comments without any as an empty listing.
+func (l *commentListing) UnmarshalJSON(b []byte) error {
+	if string(b) == `""` {
+		*l = commentListing{}
+		return nil
+	}
+	type listing commentListing
+	return json.Unmarshal(b, (*listing)(l))
+}
+
+// commentsPage is the response of a submission's comments page: a listing
+// holding the submission, then the listing of its top-level comments.
+type commentsPage []commentListing
+
+// comments returns the comment tree of the page, linking to the WWW host
+// web.
+func (p commentsPage) comments(web string) []*Comment {
+	if len(p) < 2 {
+		return nil
+	}
+	return p[1].comments(web)
 }
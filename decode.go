@@ -0,0 +1,29 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// jsonGetter is implemented by the sessions, which decode the responses of
+// GET requests as they are read rather than buffering them.
+type jsonGetter interface {
+	getJSON(v interface{}, params *url.Values, urlformat string, urlvars ...interface{}) error
+}
+
+// getJSON performs a GET request with c and decodes the response into v,
+// streaming it if c supports it.
+func getJSON(c Client, v interface{}, params *url.Values, urlformat string, urlvars ...interface{}) error {
+	if g, ok := c.(jsonGetter); ok {
+		return g.getJSON(v, params, urlformat, urlvars...)
+	}
+	body, err := c.Get(params, urlformat, urlvars...)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(body).Decode(v)
+}
@@ -0,0 +1,219 @@
+package geddit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// largeThreadSize is the number of comments of the large thread fixture.
+const largeThreadSize = 10000
+
+// largeThreadPath is the cassette of the comments page of a thread of
+// largeThreadSize comments, in chains of ten replies, after the comments
+// of geddittest's fixtures. It was recorded from geddittest with -update.
+const largeThreadPath = "testdata/large_thread.json.gz"
+
+var update = flag.Bool("update", false, "re-record the cassettes in testdata")
+
+// largeThread returns a cassette replaying the large thread fixture.
+func largeThread(tb testing.TB) *geddittest.Cassette {
+	f, err := os.Open(largeThreadPath)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	path := filepath.Join(tb.TempDir(), "large_thread.json")
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		tb.Fatal(err)
+	}
+	c, err := geddittest.LoadCassette(path, geddittest.Lenient)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// TestRecordLargeThread re-records the large thread fixture with -update.
+func TestRecordLargeThread(t *testing.T) {
+	if !*update {
+		t.Skip("run with -update to re-record " + largeThreadPath)
+	}
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	body := strings.Repeat("All work and no play makes Jack a dull gopher. ", 4)
+	parent := ""
+	for i := 0; i < largeThreadSize; i++ {
+		if i%10 == 0 {
+			parent = ""
+		}
+		id := srv.AddComment(&geddittest.Comment{
+			LinkID:   "l1",
+			ParentID: parent,
+			Author:   fmt.Sprintf("gopher%d", i%100),
+			Body:     body,
+			Score:    i % 50,
+		})
+		parent = "t1_" + id
+	}
+
+	path := filepath.Join(t.TempDir(), "large_thread.json")
+	rec := geddittest.RecordCassette(path, srv.Client())
+	s := NewSession("geddit tests")
+	s.SetHTTPClient(rec)
+	if _, err := s.Comments(&Submission{ID: "l1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(b)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(largeThreadPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countComments(comments []*Comment) int {
+	n := len(comments)
+	for _, c := range comments {
+		n += countComments(c.Replies)
+	}
+	return n
+}
+
+func TestCommentsLargeThread(t *testing.T) {
+	if testing.Short() {
+		t.Skip("decodes a large thread")
+	}
+	s := NewSession("geddit tests")
+	s.SetHTTPClient(largeThread(t))
+
+	comments, err := s.Comments(&Submission{ID: "l1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fixture's own comments come first: c1 with its reply c2, c3
+	// being collapsed.
+	if n := countComments(comments); n != largeThreadSize+2 {
+		t.Errorf("got %d comments, want %d", n, largeThreadSize+2)
+	}
+	c := comments[1]
+	for depth := 1; depth < 10; depth++ {
+		if len(c.Replies) != 1 {
+			t.Fatalf("comment at depth %d has %d replies, want 1", depth, len(c.Replies))
+		}
+		c = c.Replies[0]
+	}
+	if c.Author != "gopher9" || c.FullID == "" || c.Edited || c.Likes != nil {
+		t.Errorf("deepest comment was decoded as %+v", c)
+	}
+}
+
+func TestCommentsPageDecoding(t *testing.T) {
+	page := `[{"kind": "Listing", "data": {"children": []}}, {"kind": "Listing", "data": {"children": [
+		{"kind": "t1", "data": {"name": "t1_a", "replies": {"kind": "Listing", "data": {"children": [
+			{"kind": "t1", "data": {"name": "t1_b", "replies": ""}},
+			{"kind": "more", "data": {"name": "t1_c", "children": ["c"]}}
+		]}}}},
+		{"kind": "t1", "data": {"name": "t1_d", "replies": "", "edited": 1451610000}}
+	]}}]`
+	var p commentsPage
+	if err := json.Unmarshal([]byte(page), &p); err != nil {
+		t.Fatal(err)
+	}
+	comments := p.comments("https://reddit.example")
+	if len(comments) != 2 || len(comments[0].Replies) != 1 || len(comments[1].Replies) != 0 {
+		t.Fatalf("got comments %v", comments)
+	}
+	if b := comments[0].Replies[0]; b.FullID != "t1_b" || b.web != "https://reddit.example" {
+		t.Errorf("got reply %+v", b)
+	}
+	if !comments[1].Edited {
+		t.Error("edited comment was not marked edited")
+	}
+
+	// Type errors are reported, including after comments without replies.
+	for _, name := range []string{"t1_a", "t1_d"} {
+		bad := strings.Replace(page, `"name": "`+name+`",`, `"name": "`+name+`", "ups": "many",`, 1)
+		if err := json.Unmarshal([]byte(bad), &p); err == nil {
+			t.Errorf("got no error for a string score of %s", name)
+		}
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("decodes a large thread")
+	}
+	s := NewSession("geddit tests")
+	s.SetHTTPClient(largeThread(t))
+	s.Use(MaxResponseSize(1 << 20))
+
+	_, err := s.Comments(&Submission{ID: "l1"})
+	if _, ok := err.(*ResponseTooLargeError); !ok {
+		t.Errorf("got %v, want a *ResponseTooLargeError", err)
+	}
+}
+
+func BenchmarkCommentsLargeThread(b *testing.B) {
+	s := NewSession("geddit benchmarks")
+	s.SetHTTPClient(largeThread(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Comments(&Submission{ID: "l1"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCommentsLargeThreadGeneric measures what Comments used to do
+// before walking the tree: buffer the response, then decode it into
+// interface{} values.
+func BenchmarkCommentsLargeThreadGeneric(b *testing.B) {
+	s := NewSession("geddit benchmarks")
+	s.SetHTTPClient(largeThread(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := &request{
+			url:       s.endpoints.rurl("/comments/%s/.json", "l1"),
+			useragent: s.useragent,
+			doer:      s.doer(),
+		}
+		body, err := req.getResponse()
+		if err != nil {
+			b.Fatal(err)
+		}
+		var v interface{}
+		if err = json.NewDecoder(body).Decode(&v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Endpoints is the set of base URLs a session sends its requests to, e.g.
+// to go through a proxy or talk to a test server. Empty fields stand for
+// the ones of DefaultEndpoints.
+type Endpoints struct {
+	// WWW serves the web pages whose JSON versions Session and
+	// LoginSession read, and is the host of the permalinks and subreddit
+	// URLs of the things they fetch. OAuthSession links to it too.
+	WWW string
+	// OAuth serves the API OAuthSession calls.
+	OAuth string
+	// Token serves the OAuth access token and revocation endpoints.
+	Token string
+}
+
+// DefaultEndpoints are reddit's own endpoints, all over HTTPS.
+var DefaultEndpoints = Endpoints{
+	WWW:   BASE_URL,
+	OAuth: OAUTH_BASE_URL,
+	Token: "https://www.reddit.com",
+}
+
+// withDefaults returns e with its empty fields set from DefaultEndpoints,
+// and trailing slashes trimmed.
+func (e Endpoints) withDefaults() Endpoints {
+	fill := func(v *string, def string) {
+		if *v == "" {
+			*v = def
+		}
+		*v = strings.TrimSuffix(*v, "/")
+	}
+	fill(&e.WWW, DefaultEndpoints.WWW)
+	fill(&e.OAuth, DefaultEndpoints.OAuth)
+	fill(&e.Token, DefaultEndpoints.Token)
+	return e
+}
+
+// rurl returns the URL of a page of the WWW host.
+func (e Endpoints) rurl(format string, args ...interface{}) string {
+	return e.withDefaults().WWW + fmt.Sprintf(format, args...)
+}
+
+// jsonURL returns the URL of the JSON version of a page of the WWW host,
+// with params as its query string.
+func (e Endpoints) jsonURL(params *url.Values, format string, args ...interface{}) string {
+	u := e.rurl(format, args...)
+	if !strings.HasSuffix(u, ".json") {
+		u = strings.TrimSuffix(u, "/") + ".json"
+	}
+	if params != nil && len(*params) > 0 {
+		u += "?" + params.Encode()
+	}
+	return u
+}
+
+// ourl returns the URL of an endpoint of the OAuth API.
+func (e Endpoints) ourl(format string, args ...interface{}) string {
+	return e.withDefaults().OAuth + fmt.Sprintf(format, args...)
+}
+
+// tokenURL returns the URL of an endpoint of the Token host.
+func (e Endpoints) tokenURL(path string) string {
+	return e.withDefaults().Token + path
+}
+
+// webURL returns path on the given WWW host, or on the default one.
+func webURL(web, path string) string {
+	if web == "" {
+		web = DefaultEndpoints.WWW
+	}
+	return strings.TrimSuffix(web, "/") + path
+}
+
+// webHost returns the WWW host of the session c.
+func webHost(c Client) string {
+	if e, ok := c.(interface{ Endpoints() Endpoints }); ok {
+		return e.Endpoints().WWW
+	}
+	return DefaultEndpoints.WWW
+}
+
+// SetEndpoints makes the session send its requests to, and build URLs
+// from, the given endpoints.
+func (s *Session) SetEndpoints(e Endpoints) {
+	s.endpoints = e
+}
+
+// Endpoints returns the session's endpoints, with defaults filled in.
+func (s Session) Endpoints() Endpoints {
+	return s.endpoints.withDefaults()
+}
+
+// SetEndpoints makes the session send its requests to, and build URLs
+// from, the given endpoints. Use WithEndpoints to also request the first
+// access token from them.
+func (s *OAuthSession) SetEndpoints(e Endpoints) {
+	s.endpoints = e
+}
+
+// Endpoints returns the session's endpoints, with defaults filled in.
+func (s *OAuthSession) Endpoints() Endpoints {
+	return s.endpoints.withDefaults()
+}
+
+// WithEndpoints makes the session use the given endpoints, including for
+// its first token request.
+func WithEndpoints(e Endpoints) OAuthOption {
+	return func(s *OAuthSession) {
+		s.SetEndpoints(e)
+	}
+}
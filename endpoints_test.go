@@ -0,0 +1,127 @@
+package geddit
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// onlyHost fails the requests that are not sent to the host of base.
+func onlyHost(base string) Middleware {
+	host := strings.TrimPrefix(base, "http://")
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host != host {
+				return nil, fmt.Errorf("request sent to %s", req.URL)
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+func TestEndpointsURLs(t *testing.T) {
+	e := Endpoints{WWW: "https://proxy.example/reddit%20www/", OAuth: "https://proxy.example/%s"}
+	if got, want := e.rurl("/r/%s/about.json", "golang"), "https://proxy.example/reddit%20www/r/golang/about.json"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := e.ourl("/r/%s/about", "golang"), "https://proxy.example/%s/r/golang/about"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := e.jsonURL(&url.Values{"limit": {"5"}}, "/user/%s/about", "gopher"), "https://proxy.example/reddit%20www/user/gopher/about.json?limit=5"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := e.tokenURL("/api/v1/access_token"), DefaultEndpoints.Token+"/api/v1/access_token"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSessionEndpoints(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s := NewSession("geddit tests")
+	s.SetHTTPClient(&http.Client{})
+	s.Use(onlyHost(srv.URL))
+	s.SetEndpoints(Endpoints{WWW: srv.URL})
+
+	subreddit, err := s.AboutSubreddit("golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(subreddit.FullURL(), srv.URL+"/") {
+		t.Errorf("got subreddit URL %q", subreddit.FullURL())
+	}
+	submissions, err := s.SubredditSubmissions("golang", NewSubmissions, ListingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(submissions) == 0 || !strings.HasPrefix(submissions[0].FullPermalink(), srv.URL+"/") {
+		t.Fatalf("got submissions %v", submissions)
+	}
+	if _, err := s.DefaultFrontpage(DefaultPopularity, ListingOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	comments, err := s.Comments(&Submission{ID: "l1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) == 0 || !strings.HasPrefix(comments[0].FullPermalink(), srv.URL+"/") {
+		t.Fatalf("got comments %v", comments)
+	}
+	if _, err := s.AboutRedditor(geddittest.Username); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOAuthSessionEndpoints(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	e := Endpoints{WWW: srv.URL, OAuth: srv.URL, Token: srv.URL}
+	s, err := NewScriptLoginSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(&http.Client{}),
+		WithMiddleware(onlyHost(srv.URL)), WithEndpoints(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Me(); err != nil {
+		t.Fatal(err)
+	}
+	subreddit, err := s.AboutSubreddit("golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(subreddit.FullURL(), srv.URL+"/") {
+		t.Errorf("got subreddit URL %q", subreddit.FullURL())
+	}
+	submissions, err := s.SubredditSubmissions("golang", NewSubmissions, ListingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(submissions) == 0 || !strings.HasPrefix(submissions[0].FullPermalink(), srv.URL+"/") {
+		t.Fatalf("got submissions %v", submissions)
+	}
+	comments, err := s.Comments(&Submission{ID: "l1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) == 0 || !strings.HasPrefix(comments[0].FullPermalink(), srv.URL+"/") {
+		t.Fatalf("got comments %v", comments)
+	}
+	if err := s.Vote(submissions[0], UpVote); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MySubmitted(NewSubmissions, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	// Renewing the revoked token goes to the Token endpoint too.
+	if _, err := s.Me(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/google/go-querystring/query"
+)
+
+// flairType represents the two kinds of flair a subreddit can define.
+type flairType string
+
+const (
+	UserFlairType flairType = "USER_FLAIR"
+	LinkFlairType flairType = "LINK_FLAIR"
+)
+
+// flairCSVLimit is the maximum number of rows reddit accepts per flaircsv call.
+const flairCSVLimit = 100
+
+// FlairTemplate represents a user or link flair template of a subreddit.
+type FlairTemplate struct {
+	ID               string `json:"id"`
+	Type             string `json:"type"`
+	Text             string `json:"text"`
+	TextEditable     bool   `json:"text_editable"`
+	TextColor        string `json:"text_color"`
+	BackgroundColor  string `json:"background_color"`
+	CSSClass         string `json:"css_class"`
+	AllowableContent string `json:"allowable_content"`
+	MaxEmojis        int    `json:"max_emojis"`
+	ModOnly          bool   `json:"mod_only"`
+	OverrideCSS      bool   `json:"override_css"`
+}
+
+// String returns the string representation of a flair template.
+func (t *FlairTemplate) String() string {
+	return fmt.Sprintf("%s (%s)", t.Text, t.ID)
+}
+
+// UserFlair represents the flair assigned to a user in a subreddit.
+type UserFlair struct {
+	User     string `json:"user"`
+	Text     string `json:"flair_text"`
+	CSSClass string `json:"flair_css_class"`
+}
+
+// FlairSelection holds the parameters of a SelectFlair call. Exactly one of
+// Link and Name must be set, to flair a submission or a user respectively.
+type FlairSelection struct {
+	TemplateID      string `url:"flair_template_id,omitempty"`
+	Link            string `url:"link,omitempty"`
+	Name            string `url:"name,omitempty"`
+	Text            string `url:"text,omitempty"`
+	CSSClass        string `url:"css_class,omitempty"`
+	BackgroundColor string `url:"background_color,omitempty"`
+	TextColor       string `url:"text_color,omitempty"`
+}
+
+// FlairCSVResult is the outcome of a single row of a SetFlairCSV call.
+type FlairCSVResult struct {
+	OK       bool              `json:"ok"`
+	Status   string            `json:"status"`
+	Errors   map[string]string `json:"errors"`
+	Warnings map[string]string `json:"warnings"`
+}
+
+// flairTemplates fetches the link or user flair templates of a subreddit.
+func (s *OAuthSession) flairTemplates(subreddit, endpoint string) ([]*FlairTemplate, error) {
+	body, err := s.Get(nil, "/r/%s/api/%s", subreddit, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []*FlairTemplate
+	if err = json.NewDecoder(body).Decode(&templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// LinkFlairTemplates returns the submission flair templates of a subreddit.
+func (s *OAuthSession) LinkFlairTemplates(subreddit string) ([]*FlairTemplate, error) {
+	if err := s.requireScope(FlairScope); err != nil {
+		return nil, err
+	}
+	return s.flairTemplates(subreddit, "link_flair_v2")
+}
+
+// UserFlairTemplates returns the user flair templates of a subreddit.
+func (s *OAuthSession) UserFlairTemplates(subreddit string) ([]*FlairTemplate, error) {
+	if err := s.requireScope(FlairScope); err != nil {
+		return nil, err
+	}
+	return s.flairTemplates(subreddit, "user_flair_v2")
+}
+
+// SelectFlair sets the flair of a submission or user in a subreddit.
+func (s *OAuthSession) SelectFlair(subreddit string, sel FlairSelection) error {
+	if err := s.requireScope(FlairScope); err != nil {
+		return err
+	}
+	v, err := query.Values(sel)
+	if err != nil {
+		return err
+	}
+	_, err = s.apiPost(&v, "/r/%s/api/selectflair", subreddit)
+	return err
+}
+
+// SaveFlairTemplate creates a flair template of the given type, or updates
+// it if t.ID is set, and returns the template as stored by reddit.
+func (s *OAuthSession) SaveFlairTemplate(subreddit string, kind flairType, t *FlairTemplate) (*FlairTemplate, error) {
+	if err := s.requireScope(ModFlairScope); err != nil {
+		return nil, err
+	}
+	v := &url.Values{
+		"flair_type":    {string(kind)},
+		"text":          {t.Text},
+		"text_editable": {strconv.FormatBool(t.TextEditable)},
+		"mod_only":      {strconv.FormatBool(t.ModOnly)},
+		"override_css":  {strconv.FormatBool(t.OverrideCSS)},
+		"api_type":      {"json"},
+	}
+	for key, val := range map[string]string{
+		"flair_template_id": t.ID,
+		"text_color":        t.TextColor,
+		"background_color":  t.BackgroundColor,
+		"css_class":         t.CSSClass,
+		"allowable_content": t.AllowableContent,
+	} {
+		if val != "" {
+			v.Set(key, val)
+		}
+	}
+	if t.MaxEmojis > 0 {
+		v.Set("max_emojis", strconv.Itoa(t.MaxEmojis))
+	}
+
+	body, err := s.Post(v, "/r/%s/api/flairtemplate_v2", subreddit)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &FlairTemplate{}
+	if err = json.NewDecoder(body).Decode(saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// DeleteFlairTemplate deletes a flair template of a subreddit.
+func (s *OAuthSession) DeleteFlairTemplate(subreddit, id string) error {
+	if err := s.requireScope(ModFlairScope); err != nil {
+		return err
+	}
+	_, err := s.apiPost(&url.Values{
+		"flair_template_id": {id},
+	}, "/r/%s/api/deleteflairtemplate", subreddit)
+	return err
+}
+
+// SetFlairCSV sets the flair of many users at once. The flairs are sent in
+// batches of 100, the most reddit accepts per call, and the returned results
+// are in the same order as flairs.
+func (s *OAuthSession) SetFlairCSV(subreddit string, flairs []*UserFlair) ([]*FlairCSVResult, error) {
+	if err := s.requireScope(ModFlairScope); err != nil {
+		return nil, err
+	}
+	results := make([]*FlairCSVResult, 0, len(flairs))
+	for start := 0; start < len(flairs); start += flairCSVLimit {
+		end := start + flairCSVLimit
+		if end > len(flairs) {
+			end = len(flairs)
+		}
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		for _, f := range flairs[start:end] {
+			if err := w.Write([]string{f.User, f.Text, f.CSSClass}); err != nil {
+				return results, err
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return results, err
+		}
+
+		body, err := s.Post(&url.Values{
+			"flair_csv": {buf.String()},
+		}, "/r/%s/api/flaircsv", subreddit)
+		if err != nil {
+			return results, err
+		}
+
+		var batch []*FlairCSVResult
+		if err = json.NewDecoder(body).Decode(&batch); err != nil {
+			return results, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+// FlairListIterator iterates over the user flairs of a subreddit, in the
+// same fashion as RelationshipIterator.
+type FlairListIterator struct {
+	session   *OAuthSession
+	subreddit string
+	params    url.Values
+	page      []*UserFlair
+	next      string
+	started   bool
+	cur       *UserFlair
+	err       error
+}
+
+// Next advances the iterator to the next user flair. It returns false when
+// there are no more flairs or an error occurred.
+func (it *FlairListIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || (it.started && it.next == "") {
+			return false
+		}
+		it.fetch()
+	}
+	it.cur = it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+// fetch loads the next page of the flair list.
+func (it *FlairListIterator) fetch() {
+	if it.started {
+		it.params.Set("after", it.next)
+		it.params.Del("before")
+	}
+	it.started = true
+
+	body, err := it.session.Get(&it.params, "/r/%s/api/flairlist", it.subreddit)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	type Response struct {
+		Users []*UserFlair `json:"users"`
+		Next  string       `json:"next"`
+		Prev  string       `json:"prev"`
+	}
+	r := &Response{}
+	if it.err = json.NewDecoder(body).Decode(r); it.err != nil {
+		return
+	}
+	it.page = r.Users
+	it.next = r.Next
+}
+
+// Flair returns the user flair the iterator currently points to.
+func (it *FlairListIterator) Flair() *UserFlair {
+	return it.cur
+}
+
+// After returns the value to pass as ListingOptions.After to resume the
+// listing after the last page fetched.
+func (it *FlairListIterator) After() string {
+	return it.next
+}
+
+// Err returns the first error encountered by the iterator.
+func (it *FlairListIterator) Err() error {
+	return it.err
+}
+
+// FlairList returns an iterator over the user flairs of a subreddit. If
+// username is not empty only that user's flair is returned.
+func (s *OAuthSession) FlairList(subreddit, username string, params ListingOptions) *FlairListIterator {
+	if err := s.requireScope(ModFlairScope); err != nil {
+		return &FlairListIterator{err: err}
+	}
+	it := &FlairListIterator{
+		session:   s,
+		subreddit: subreddit,
+	}
+	v, err := query.Values(params)
+	if err != nil {
+		it.err = err
+		return it
+	}
+	if username != "" {
+		v.Set("name", username)
+	}
+	it.params = v
+	return it
+}
@@ -0,0 +1,77 @@
+package geddit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestSetFlairCSV(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, err := NewOAuthSession(geddittest.Moderator, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flairs := []*UserFlair{
+		{User: geddittest.Username, Text: "Gopher", CSSClass: "blue"},
+		{User: "nobody", Text: "Ghost"},
+		{User: geddittest.Moderator, Text: strings.Repeat("x", 70)},
+	}
+	// Enough rows to need a second batch, which ends with rows that
+	// succeed.
+	for i := len(flairs); i < flairCSVLimit+1; i++ {
+		flairs = append(flairs, &UserFlair{User: fmt.Sprintf("nobody%d", i), Text: "Ghost"})
+	}
+	flairs = append(flairs,
+		&UserFlair{User: geddittest.Moderator, Text: "Mod", CSSClass: "green"},
+		&UserFlair{User: geddittest.Username},
+	)
+
+	sent := srv.Requests()
+	results, err := s.SetFlairCSV("golang", flairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests() - sent; n != 2 {
+		t.Errorf("sent %d requests for %d rows, want 2", n, len(flairs))
+	}
+	if len(results) != len(flairs) {
+		t.Fatalf("got %d results for %d rows", len(results), len(flairs))
+	}
+	checks := []struct {
+		row      int
+		ok       bool
+		errorKey string
+		status   string
+	}{
+		{0, true, "", "added flair for user " + geddittest.Username},
+		{1, false, "user", "skipped"},
+		{2, false, "text", "skipped"},
+		{flairCSVLimit, false, "user", "skipped"},
+		{flairCSVLimit + 1, true, "", "added flair for user " + geddittest.Moderator},
+		{flairCSVLimit + 2, true, "", "removed flair for user " + geddittest.Username},
+	}
+	for _, c := range checks {
+		r := results[c.row]
+		if r.OK != c.ok || r.Status != c.status || (c.errorKey != "") != (r.Errors[c.errorKey] != "") {
+			t.Errorf("row %d: got %+v, want ok %v, status %q and a %q error", c.row, r, c.ok, c.status, c.errorKey)
+		}
+	}
+
+	it := s.FlairList("golang", "", ListingOptions{})
+	var got []*UserFlair
+	for it.Next() {
+		got = append(got, it.Flair())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || *got[0] != (UserFlair{User: geddittest.Moderator, Text: "Mod", CSSClass: "green"}) {
+		t.Errorf("got flairs %v after the rows applied", got)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gedditfake
+
+import (
+	"sync"
+
+	"github.com/jzelinskie/geddit"
+)
+
+// FakeActor is a fake geddit.Actor. Each method records its
+// calls and returns what its Stub returns if set, or else the values given
+// to its Returns method.
+type FakeActor struct {
+	SubmitStub        func(*geddit.NewSubmission) error
+	submitMutex       sync.RWMutex
+	submitArgsForCall []struct {
+		arg1 *geddit.NewSubmission
+	}
+	submitReturns struct {
+		result1 error
+	}
+	VoteStub        func(geddit.Voter, geddit.VoteDirection) error
+	voteMutex       sync.RWMutex
+	voteArgsForCall []struct {
+		arg1 geddit.Voter
+		arg2 geddit.VoteDirection
+	}
+	voteReturns struct {
+		result1 error
+	}
+	ReplyStub        func(geddit.Replier, string) error
+	replyMutex       sync.RWMutex
+	replyArgsForCall []struct {
+		arg1 geddit.Replier
+		arg2 string
+	}
+	replyReturns struct {
+		result1 error
+	}
+	DeleteStub        func(geddit.Deleter) error
+	deleteMutex       sync.RWMutex
+	deleteArgsForCall []struct {
+		arg1 geddit.Deleter
+	}
+	deleteReturns struct {
+		result1 error
+	}
+}
+
+// Submit implements geddit.Actor.
+func (fake *FakeActor) Submit(arg1 *geddit.NewSubmission) error {
+	fake.submitMutex.Lock()
+	fake.submitArgsForCall = append(fake.submitArgsForCall, struct {
+		arg1 *geddit.NewSubmission
+	}{arg1})
+	stub := fake.SubmitStub
+	fake.submitMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	fake.submitMutex.RLock()
+	defer fake.submitMutex.RUnlock()
+	return fake.submitReturns.result1
+}
+
+// SubmitCallCount returns the number of calls to Submit.
+func (fake *FakeActor) SubmitCallCount() int {
+	fake.submitMutex.RLock()
+	defer fake.submitMutex.RUnlock()
+	return len(fake.submitArgsForCall)
+}
+
+// SubmitArgsForCall returns the arguments of the i-th call to Submit.
+func (fake *FakeActor) SubmitArgsForCall(i int) *geddit.NewSubmission {
+	fake.submitMutex.RLock()
+	defer fake.submitMutex.RUnlock()
+	args := fake.submitArgsForCall[i]
+	return args.arg1
+}
+
+// SubmitReturns sets the values returned by Submit when it has no stub.
+func (fake *FakeActor) SubmitReturns(result1 error) {
+	fake.submitMutex.Lock()
+	defer fake.submitMutex.Unlock()
+	fake.SubmitStub = nil
+	fake.submitReturns.result1 = result1
+}
+
+// Vote implements geddit.Actor.
+func (fake *FakeActor) Vote(arg1 geddit.Voter, arg2 geddit.VoteDirection) error {
+	fake.voteMutex.Lock()
+	fake.voteArgsForCall = append(fake.voteArgsForCall, struct {
+		arg1 geddit.Voter
+		arg2 geddit.VoteDirection
+	}{arg1, arg2})
+	stub := fake.VoteStub
+	fake.voteMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	fake.voteMutex.RLock()
+	defer fake.voteMutex.RUnlock()
+	return fake.voteReturns.result1
+}
+
+// VoteCallCount returns the number of calls to Vote.
+func (fake *FakeActor) VoteCallCount() int {
+	fake.voteMutex.RLock()
+	defer fake.voteMutex.RUnlock()
+	return len(fake.voteArgsForCall)
+}
+
+// VoteArgsForCall returns the arguments of the i-th call to Vote.
+func (fake *FakeActor) VoteArgsForCall(i int) (geddit.Voter, geddit.VoteDirection) {
+	fake.voteMutex.RLock()
+	defer fake.voteMutex.RUnlock()
+	args := fake.voteArgsForCall[i]
+	return args.arg1, args.arg2
+}
+
+// VoteReturns sets the values returned by Vote when it has no stub.
+func (fake *FakeActor) VoteReturns(result1 error) {
+	fake.voteMutex.Lock()
+	defer fake.voteMutex.Unlock()
+	fake.VoteStub = nil
+	fake.voteReturns.result1 = result1
+}
+
+// Reply implements geddit.Actor.
+func (fake *FakeActor) Reply(arg1 geddit.Replier, arg2 string) error {
+	fake.replyMutex.Lock()
+	fake.replyArgsForCall = append(fake.replyArgsForCall, struct {
+		arg1 geddit.Replier
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.ReplyStub
+	fake.replyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	fake.replyMutex.RLock()
+	defer fake.replyMutex.RUnlock()
+	return fake.replyReturns.result1
+}
+
+// ReplyCallCount returns the number of calls to Reply.
+func (fake *FakeActor) ReplyCallCount() int {
+	fake.replyMutex.RLock()
+	defer fake.replyMutex.RUnlock()
+	return len(fake.replyArgsForCall)
+}
+
+// ReplyArgsForCall returns the arguments of the i-th call to Reply.
+func (fake *FakeActor) ReplyArgsForCall(i int) (geddit.Replier, string) {
+	fake.replyMutex.RLock()
+	defer fake.replyMutex.RUnlock()
+	args := fake.replyArgsForCall[i]
+	return args.arg1, args.arg2
+}
+
+// ReplyReturns sets the values returned by Reply when it has no stub.
+func (fake *FakeActor) ReplyReturns(result1 error) {
+	fake.replyMutex.Lock()
+	defer fake.replyMutex.Unlock()
+	fake.ReplyStub = nil
+	fake.replyReturns.result1 = result1
+}
+
+// Delete implements geddit.Actor.
+func (fake *FakeActor) Delete(arg1 geddit.Deleter) error {
+	fake.deleteMutex.Lock()
+	fake.deleteArgsForCall = append(fake.deleteArgsForCall, struct {
+		arg1 geddit.Deleter
+	}{arg1})
+	stub := fake.DeleteStub
+	fake.deleteMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return fake.deleteReturns.result1
+}
+
+// DeleteCallCount returns the number of calls to Delete.
+func (fake *FakeActor) DeleteCallCount() int {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return len(fake.deleteArgsForCall)
+}
+
+// DeleteArgsForCall returns the arguments of the i-th call to Delete.
+func (fake *FakeActor) DeleteArgsForCall(i int) geddit.Deleter {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	args := fake.deleteArgsForCall[i]
+	return args.arg1
+}
+
+// DeleteReturns sets the values returned by Delete when it has no stub.
+func (fake *FakeActor) DeleteReturns(result1 error) {
+	fake.deleteMutex.Lock()
+	defer fake.deleteMutex.Unlock()
+	fake.DeleteStub = nil
+	fake.deleteReturns.result1 = result1
+}
+
+var _ geddit.Actor = new(FakeActor)
@@ -0,0 +1,15 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gedditfake provides fakes of the geddit.Reader, geddit.Actor,
+// geddit.Messenger and geddit.Moderator interfaces, for unit testing code
+// that uses reddit without any HTTP at all:
+//
+//	reader := new(gedditfake.FakeReader)
+//	reader.AboutSubredditReturns(&geddit.Subreddit{Name: "golang"}, nil)
+//	// ... exercise code taking a geddit.Reader ...
+//	if reader.AboutSubredditCallCount() != 1 { ... }
+//
+// For tests exercising the HTTP layer, see package geddittest instead.
+package gedditfake
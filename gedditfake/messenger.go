@@ -0,0 +1,166 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gedditfake
+
+import (
+	"sync"
+
+	"github.com/jzelinskie/geddit"
+)
+
+// FakeMessenger is a fake geddit.Messenger. Each method records its
+// calls and returns what its Stub returns if set, or else the values given
+// to its Returns method.
+type FakeMessenger struct {
+	SendMessageStub        func(string, string, string) error
+	sendMessageMutex       sync.RWMutex
+	sendMessageArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}
+	sendMessageReturns struct {
+		result1 error
+	}
+	InboxStub        func(geddit.ListingOptions) ([]*geddit.Message, error)
+	inboxMutex       sync.RWMutex
+	inboxArgsForCall []struct {
+		arg1 geddit.ListingOptions
+	}
+	inboxReturns struct {
+		result1 []*geddit.Message
+		result2 error
+	}
+	MarkMessagesReadStub        func(...string) error
+	markMessagesReadMutex       sync.RWMutex
+	markMessagesReadArgsForCall []struct {
+		arg1 []string
+	}
+	markMessagesReadReturns struct {
+		result1 error
+	}
+}
+
+// SendMessage implements geddit.Messenger.
+func (fake *FakeMessenger) SendMessage(arg1 string, arg2 string, arg3 string) error {
+	fake.sendMessageMutex.Lock()
+	fake.sendMessageArgsForCall = append(fake.sendMessageArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.SendMessageStub
+	fake.sendMessageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	fake.sendMessageMutex.RLock()
+	defer fake.sendMessageMutex.RUnlock()
+	return fake.sendMessageReturns.result1
+}
+
+// SendMessageCallCount returns the number of calls to SendMessage.
+func (fake *FakeMessenger) SendMessageCallCount() int {
+	fake.sendMessageMutex.RLock()
+	defer fake.sendMessageMutex.RUnlock()
+	return len(fake.sendMessageArgsForCall)
+}
+
+// SendMessageArgsForCall returns the arguments of the i-th call to SendMessage.
+func (fake *FakeMessenger) SendMessageArgsForCall(i int) (string, string, string) {
+	fake.sendMessageMutex.RLock()
+	defer fake.sendMessageMutex.RUnlock()
+	args := fake.sendMessageArgsForCall[i]
+	return args.arg1, args.arg2, args.arg3
+}
+
+// SendMessageReturns sets the values returned by SendMessage when it has no stub.
+func (fake *FakeMessenger) SendMessageReturns(result1 error) {
+	fake.sendMessageMutex.Lock()
+	defer fake.sendMessageMutex.Unlock()
+	fake.SendMessageStub = nil
+	fake.sendMessageReturns.result1 = result1
+}
+
+// Inbox implements geddit.Messenger.
+func (fake *FakeMessenger) Inbox(arg1 geddit.ListingOptions) ([]*geddit.Message, error) {
+	fake.inboxMutex.Lock()
+	fake.inboxArgsForCall = append(fake.inboxArgsForCall, struct {
+		arg1 geddit.ListingOptions
+	}{arg1})
+	stub := fake.InboxStub
+	fake.inboxMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	fake.inboxMutex.RLock()
+	defer fake.inboxMutex.RUnlock()
+	return fake.inboxReturns.result1, fake.inboxReturns.result2
+}
+
+// InboxCallCount returns the number of calls to Inbox.
+func (fake *FakeMessenger) InboxCallCount() int {
+	fake.inboxMutex.RLock()
+	defer fake.inboxMutex.RUnlock()
+	return len(fake.inboxArgsForCall)
+}
+
+// InboxArgsForCall returns the arguments of the i-th call to Inbox.
+func (fake *FakeMessenger) InboxArgsForCall(i int) geddit.ListingOptions {
+	fake.inboxMutex.RLock()
+	defer fake.inboxMutex.RUnlock()
+	args := fake.inboxArgsForCall[i]
+	return args.arg1
+}
+
+// InboxReturns sets the values returned by Inbox when it has no stub.
+func (fake *FakeMessenger) InboxReturns(result1 []*geddit.Message, result2 error) {
+	fake.inboxMutex.Lock()
+	defer fake.inboxMutex.Unlock()
+	fake.InboxStub = nil
+	fake.inboxReturns.result1 = result1
+	fake.inboxReturns.result2 = result2
+}
+
+// MarkMessagesRead implements geddit.Messenger.
+func (fake *FakeMessenger) MarkMessagesRead(arg1 ...string) error {
+	fake.markMessagesReadMutex.Lock()
+	fake.markMessagesReadArgsForCall = append(fake.markMessagesReadArgsForCall, struct {
+		arg1 []string
+	}{arg1})
+	stub := fake.MarkMessagesReadStub
+	fake.markMessagesReadMutex.Unlock()
+	if stub != nil {
+		return stub(arg1...)
+	}
+	fake.markMessagesReadMutex.RLock()
+	defer fake.markMessagesReadMutex.RUnlock()
+	return fake.markMessagesReadReturns.result1
+}
+
+// MarkMessagesReadCallCount returns the number of calls to MarkMessagesRead.
+func (fake *FakeMessenger) MarkMessagesReadCallCount() int {
+	fake.markMessagesReadMutex.RLock()
+	defer fake.markMessagesReadMutex.RUnlock()
+	return len(fake.markMessagesReadArgsForCall)
+}
+
+// MarkMessagesReadArgsForCall returns the arguments of the i-th call to MarkMessagesRead.
+func (fake *FakeMessenger) MarkMessagesReadArgsForCall(i int) []string {
+	fake.markMessagesReadMutex.RLock()
+	defer fake.markMessagesReadMutex.RUnlock()
+	args := fake.markMessagesReadArgsForCall[i]
+	return args.arg1
+}
+
+// MarkMessagesReadReturns sets the values returned by MarkMessagesRead when it has no stub.
+func (fake *FakeMessenger) MarkMessagesReadReturns(result1 error) {
+	fake.markMessagesReadMutex.Lock()
+	defer fake.markMessagesReadMutex.Unlock()
+	fake.MarkMessagesReadStub = nil
+	fake.markMessagesReadReturns.result1 = result1
+}
+
+var _ geddit.Messenger = new(FakeMessenger)
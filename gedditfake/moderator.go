@@ -0,0 +1,219 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gedditfake
+
+import (
+	"sync"
+
+	"github.com/jzelinskie/geddit"
+)
+
+// FakeModerator is a fake geddit.Moderator. Each method records its
+// calls and returns what its Stub returns if set, or else the values given
+// to its Returns method.
+type FakeModerator struct {
+	ApproveStub        func(string) error
+	approveMutex       sync.RWMutex
+	approveArgsForCall []struct {
+		arg1 string
+	}
+	approveReturns struct {
+		result1 error
+	}
+	RemoveStub        func(string, bool) error
+	removeMutex       sync.RWMutex
+	removeArgsForCall []struct {
+		arg1 string
+		arg2 bool
+	}
+	removeReturns struct {
+		result1 error
+	}
+	FriendStub        func(string, string, geddit.RelationshipType, geddit.FriendOptions) error
+	friendMutex       sync.RWMutex
+	friendArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 geddit.RelationshipType
+		arg4 geddit.FriendOptions
+	}
+	friendReturns struct {
+		result1 error
+	}
+	UnfriendStub        func(string, string, geddit.RelationshipType) error
+	unfriendMutex       sync.RWMutex
+	unfriendArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 geddit.RelationshipType
+	}
+	unfriendReturns struct {
+		result1 error
+	}
+}
+
+// Approve implements geddit.Moderator.
+func (fake *FakeModerator) Approve(arg1 string) error {
+	fake.approveMutex.Lock()
+	fake.approveArgsForCall = append(fake.approveArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ApproveStub
+	fake.approveMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	fake.approveMutex.RLock()
+	defer fake.approveMutex.RUnlock()
+	return fake.approveReturns.result1
+}
+
+// ApproveCallCount returns the number of calls to Approve.
+func (fake *FakeModerator) ApproveCallCount() int {
+	fake.approveMutex.RLock()
+	defer fake.approveMutex.RUnlock()
+	return len(fake.approveArgsForCall)
+}
+
+// ApproveArgsForCall returns the arguments of the i-th call to Approve.
+func (fake *FakeModerator) ApproveArgsForCall(i int) string {
+	fake.approveMutex.RLock()
+	defer fake.approveMutex.RUnlock()
+	args := fake.approveArgsForCall[i]
+	return args.arg1
+}
+
+// ApproveReturns sets the values returned by Approve when it has no stub.
+func (fake *FakeModerator) ApproveReturns(result1 error) {
+	fake.approveMutex.Lock()
+	defer fake.approveMutex.Unlock()
+	fake.ApproveStub = nil
+	fake.approveReturns.result1 = result1
+}
+
+// Remove implements geddit.Moderator.
+func (fake *FakeModerator) Remove(arg1 string, arg2 bool) error {
+	fake.removeMutex.Lock()
+	fake.removeArgsForCall = append(fake.removeArgsForCall, struct {
+		arg1 string
+		arg2 bool
+	}{arg1, arg2})
+	stub := fake.RemoveStub
+	fake.removeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	fake.removeMutex.RLock()
+	defer fake.removeMutex.RUnlock()
+	return fake.removeReturns.result1
+}
+
+// RemoveCallCount returns the number of calls to Remove.
+func (fake *FakeModerator) RemoveCallCount() int {
+	fake.removeMutex.RLock()
+	defer fake.removeMutex.RUnlock()
+	return len(fake.removeArgsForCall)
+}
+
+// RemoveArgsForCall returns the arguments of the i-th call to Remove.
+func (fake *FakeModerator) RemoveArgsForCall(i int) (string, bool) {
+	fake.removeMutex.RLock()
+	defer fake.removeMutex.RUnlock()
+	args := fake.removeArgsForCall[i]
+	return args.arg1, args.arg2
+}
+
+// RemoveReturns sets the values returned by Remove when it has no stub.
+func (fake *FakeModerator) RemoveReturns(result1 error) {
+	fake.removeMutex.Lock()
+	defer fake.removeMutex.Unlock()
+	fake.RemoveStub = nil
+	fake.removeReturns.result1 = result1
+}
+
+// Friend implements geddit.Moderator.
+func (fake *FakeModerator) Friend(arg1 string, arg2 string, arg3 geddit.RelationshipType, arg4 geddit.FriendOptions) error {
+	fake.friendMutex.Lock()
+	fake.friendArgsForCall = append(fake.friendArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 geddit.RelationshipType
+		arg4 geddit.FriendOptions
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.FriendStub
+	fake.friendMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	fake.friendMutex.RLock()
+	defer fake.friendMutex.RUnlock()
+	return fake.friendReturns.result1
+}
+
+// FriendCallCount returns the number of calls to Friend.
+func (fake *FakeModerator) FriendCallCount() int {
+	fake.friendMutex.RLock()
+	defer fake.friendMutex.RUnlock()
+	return len(fake.friendArgsForCall)
+}
+
+// FriendArgsForCall returns the arguments of the i-th call to Friend.
+func (fake *FakeModerator) FriendArgsForCall(i int) (string, string, geddit.RelationshipType, geddit.FriendOptions) {
+	fake.friendMutex.RLock()
+	defer fake.friendMutex.RUnlock()
+	args := fake.friendArgsForCall[i]
+	return args.arg1, args.arg2, args.arg3, args.arg4
+}
+
+// FriendReturns sets the values returned by Friend when it has no stub.
+func (fake *FakeModerator) FriendReturns(result1 error) {
+	fake.friendMutex.Lock()
+	defer fake.friendMutex.Unlock()
+	fake.FriendStub = nil
+	fake.friendReturns.result1 = result1
+}
+
+// Unfriend implements geddit.Moderator.
+func (fake *FakeModerator) Unfriend(arg1 string, arg2 string, arg3 geddit.RelationshipType) error {
+	fake.unfriendMutex.Lock()
+	fake.unfriendArgsForCall = append(fake.unfriendArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 geddit.RelationshipType
+	}{arg1, arg2, arg3})
+	stub := fake.UnfriendStub
+	fake.unfriendMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	fake.unfriendMutex.RLock()
+	defer fake.unfriendMutex.RUnlock()
+	return fake.unfriendReturns.result1
+}
+
+// UnfriendCallCount returns the number of calls to Unfriend.
+func (fake *FakeModerator) UnfriendCallCount() int {
+	fake.unfriendMutex.RLock()
+	defer fake.unfriendMutex.RUnlock()
+	return len(fake.unfriendArgsForCall)
+}
+
+// UnfriendArgsForCall returns the arguments of the i-th call to Unfriend.
+func (fake *FakeModerator) UnfriendArgsForCall(i int) (string, string, geddit.RelationshipType) {
+	fake.unfriendMutex.RLock()
+	defer fake.unfriendMutex.RUnlock()
+	args := fake.unfriendArgsForCall[i]
+	return args.arg1, args.arg2, args.arg3
+}
+
+// UnfriendReturns sets the values returned by Unfriend when it has no stub.
+func (fake *FakeModerator) UnfriendReturns(result1 error) {
+	fake.unfriendMutex.Lock()
+	defer fake.unfriendMutex.Unlock()
+	fake.UnfriendStub = nil
+	fake.unfriendReturns.result1 = result1
+}
+
+var _ geddit.Moderator = new(FakeModerator)
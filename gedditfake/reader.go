@@ -0,0 +1,270 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gedditfake
+
+import (
+	"sync"
+
+	"github.com/jzelinskie/geddit"
+)
+
+// FakeReader is a fake geddit.Reader. Each method records its
+// calls and returns what its Stub returns if set, or else the values given
+// to its Returns method.
+type FakeReader struct {
+	DefaultFrontpageStub        func(geddit.PopularitySort, geddit.ListingOptions) ([]*geddit.Submission, error)
+	defaultFrontpageMutex       sync.RWMutex
+	defaultFrontpageArgsForCall []struct {
+		arg1 geddit.PopularitySort
+		arg2 geddit.ListingOptions
+	}
+	defaultFrontpageReturns struct {
+		result1 []*geddit.Submission
+		result2 error
+	}
+	SubredditSubmissionsStub        func(string, geddit.PopularitySort, geddit.ListingOptions) ([]*geddit.Submission, error)
+	subredditSubmissionsMutex       sync.RWMutex
+	subredditSubmissionsArgsForCall []struct {
+		arg1 string
+		arg2 geddit.PopularitySort
+		arg3 geddit.ListingOptions
+	}
+	subredditSubmissionsReturns struct {
+		result1 []*geddit.Submission
+		result2 error
+	}
+	AboutSubredditStub        func(string) (*geddit.Subreddit, error)
+	aboutSubredditMutex       sync.RWMutex
+	aboutSubredditArgsForCall []struct {
+		arg1 string
+	}
+	aboutSubredditReturns struct {
+		result1 *geddit.Subreddit
+		result2 error
+	}
+	AboutRedditorStub        func(string) (*geddit.Redditor, error)
+	aboutRedditorMutex       sync.RWMutex
+	aboutRedditorArgsForCall []struct {
+		arg1 string
+	}
+	aboutRedditorReturns struct {
+		result1 *geddit.Redditor
+		result2 error
+	}
+	CommentsStub        func(*geddit.Submission) ([]*geddit.Comment, error)
+	commentsMutex       sync.RWMutex
+	commentsArgsForCall []struct {
+		arg1 *geddit.Submission
+	}
+	commentsReturns struct {
+		result1 []*geddit.Comment
+		result2 error
+	}
+}
+
+// DefaultFrontpage implements geddit.Reader.
+func (fake *FakeReader) DefaultFrontpage(arg1 geddit.PopularitySort, arg2 geddit.ListingOptions) ([]*geddit.Submission, error) {
+	fake.defaultFrontpageMutex.Lock()
+	fake.defaultFrontpageArgsForCall = append(fake.defaultFrontpageArgsForCall, struct {
+		arg1 geddit.PopularitySort
+		arg2 geddit.ListingOptions
+	}{arg1, arg2})
+	stub := fake.DefaultFrontpageStub
+	fake.defaultFrontpageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	fake.defaultFrontpageMutex.RLock()
+	defer fake.defaultFrontpageMutex.RUnlock()
+	return fake.defaultFrontpageReturns.result1, fake.defaultFrontpageReturns.result2
+}
+
+// DefaultFrontpageCallCount returns the number of calls to DefaultFrontpage.
+func (fake *FakeReader) DefaultFrontpageCallCount() int {
+	fake.defaultFrontpageMutex.RLock()
+	defer fake.defaultFrontpageMutex.RUnlock()
+	return len(fake.defaultFrontpageArgsForCall)
+}
+
+// DefaultFrontpageArgsForCall returns the arguments of the i-th call to DefaultFrontpage.
+func (fake *FakeReader) DefaultFrontpageArgsForCall(i int) (geddit.PopularitySort, geddit.ListingOptions) {
+	fake.defaultFrontpageMutex.RLock()
+	defer fake.defaultFrontpageMutex.RUnlock()
+	args := fake.defaultFrontpageArgsForCall[i]
+	return args.arg1, args.arg2
+}
+
+// DefaultFrontpageReturns sets the values returned by DefaultFrontpage when it has no stub.
+func (fake *FakeReader) DefaultFrontpageReturns(result1 []*geddit.Submission, result2 error) {
+	fake.defaultFrontpageMutex.Lock()
+	defer fake.defaultFrontpageMutex.Unlock()
+	fake.DefaultFrontpageStub = nil
+	fake.defaultFrontpageReturns.result1 = result1
+	fake.defaultFrontpageReturns.result2 = result2
+}
+
+// SubredditSubmissions implements geddit.Reader.
+func (fake *FakeReader) SubredditSubmissions(arg1 string, arg2 geddit.PopularitySort, arg3 geddit.ListingOptions) ([]*geddit.Submission, error) {
+	fake.subredditSubmissionsMutex.Lock()
+	fake.subredditSubmissionsArgsForCall = append(fake.subredditSubmissionsArgsForCall, struct {
+		arg1 string
+		arg2 geddit.PopularitySort
+		arg3 geddit.ListingOptions
+	}{arg1, arg2, arg3})
+	stub := fake.SubredditSubmissionsStub
+	fake.subredditSubmissionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	fake.subredditSubmissionsMutex.RLock()
+	defer fake.subredditSubmissionsMutex.RUnlock()
+	return fake.subredditSubmissionsReturns.result1, fake.subredditSubmissionsReturns.result2
+}
+
+// SubredditSubmissionsCallCount returns the number of calls to SubredditSubmissions.
+func (fake *FakeReader) SubredditSubmissionsCallCount() int {
+	fake.subredditSubmissionsMutex.RLock()
+	defer fake.subredditSubmissionsMutex.RUnlock()
+	return len(fake.subredditSubmissionsArgsForCall)
+}
+
+// SubredditSubmissionsArgsForCall returns the arguments of the i-th call to SubredditSubmissions.
+func (fake *FakeReader) SubredditSubmissionsArgsForCall(i int) (string, geddit.PopularitySort, geddit.ListingOptions) {
+	fake.subredditSubmissionsMutex.RLock()
+	defer fake.subredditSubmissionsMutex.RUnlock()
+	args := fake.subredditSubmissionsArgsForCall[i]
+	return args.arg1, args.arg2, args.arg3
+}
+
+// SubredditSubmissionsReturns sets the values returned by SubredditSubmissions when it has no stub.
+func (fake *FakeReader) SubredditSubmissionsReturns(result1 []*geddit.Submission, result2 error) {
+	fake.subredditSubmissionsMutex.Lock()
+	defer fake.subredditSubmissionsMutex.Unlock()
+	fake.SubredditSubmissionsStub = nil
+	fake.subredditSubmissionsReturns.result1 = result1
+	fake.subredditSubmissionsReturns.result2 = result2
+}
+
+// AboutSubreddit implements geddit.Reader.
+func (fake *FakeReader) AboutSubreddit(arg1 string) (*geddit.Subreddit, error) {
+	fake.aboutSubredditMutex.Lock()
+	fake.aboutSubredditArgsForCall = append(fake.aboutSubredditArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.AboutSubredditStub
+	fake.aboutSubredditMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	fake.aboutSubredditMutex.RLock()
+	defer fake.aboutSubredditMutex.RUnlock()
+	return fake.aboutSubredditReturns.result1, fake.aboutSubredditReturns.result2
+}
+
+// AboutSubredditCallCount returns the number of calls to AboutSubreddit.
+func (fake *FakeReader) AboutSubredditCallCount() int {
+	fake.aboutSubredditMutex.RLock()
+	defer fake.aboutSubredditMutex.RUnlock()
+	return len(fake.aboutSubredditArgsForCall)
+}
+
+// AboutSubredditArgsForCall returns the arguments of the i-th call to AboutSubreddit.
+func (fake *FakeReader) AboutSubredditArgsForCall(i int) string {
+	fake.aboutSubredditMutex.RLock()
+	defer fake.aboutSubredditMutex.RUnlock()
+	args := fake.aboutSubredditArgsForCall[i]
+	return args.arg1
+}
+
+// AboutSubredditReturns sets the values returned by AboutSubreddit when it has no stub.
+func (fake *FakeReader) AboutSubredditReturns(result1 *geddit.Subreddit, result2 error) {
+	fake.aboutSubredditMutex.Lock()
+	defer fake.aboutSubredditMutex.Unlock()
+	fake.AboutSubredditStub = nil
+	fake.aboutSubredditReturns.result1 = result1
+	fake.aboutSubredditReturns.result2 = result2
+}
+
+// AboutRedditor implements geddit.Reader.
+func (fake *FakeReader) AboutRedditor(arg1 string) (*geddit.Redditor, error) {
+	fake.aboutRedditorMutex.Lock()
+	fake.aboutRedditorArgsForCall = append(fake.aboutRedditorArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.AboutRedditorStub
+	fake.aboutRedditorMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	fake.aboutRedditorMutex.RLock()
+	defer fake.aboutRedditorMutex.RUnlock()
+	return fake.aboutRedditorReturns.result1, fake.aboutRedditorReturns.result2
+}
+
+// AboutRedditorCallCount returns the number of calls to AboutRedditor.
+func (fake *FakeReader) AboutRedditorCallCount() int {
+	fake.aboutRedditorMutex.RLock()
+	defer fake.aboutRedditorMutex.RUnlock()
+	return len(fake.aboutRedditorArgsForCall)
+}
+
+// AboutRedditorArgsForCall returns the arguments of the i-th call to AboutRedditor.
+func (fake *FakeReader) AboutRedditorArgsForCall(i int) string {
+	fake.aboutRedditorMutex.RLock()
+	defer fake.aboutRedditorMutex.RUnlock()
+	args := fake.aboutRedditorArgsForCall[i]
+	return args.arg1
+}
+
+// AboutRedditorReturns sets the values returned by AboutRedditor when it has no stub.
+func (fake *FakeReader) AboutRedditorReturns(result1 *geddit.Redditor, result2 error) {
+	fake.aboutRedditorMutex.Lock()
+	defer fake.aboutRedditorMutex.Unlock()
+	fake.AboutRedditorStub = nil
+	fake.aboutRedditorReturns.result1 = result1
+	fake.aboutRedditorReturns.result2 = result2
+}
+
+// Comments implements geddit.Reader.
+func (fake *FakeReader) Comments(arg1 *geddit.Submission) ([]*geddit.Comment, error) {
+	fake.commentsMutex.Lock()
+	fake.commentsArgsForCall = append(fake.commentsArgsForCall, struct {
+		arg1 *geddit.Submission
+	}{arg1})
+	stub := fake.CommentsStub
+	fake.commentsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	fake.commentsMutex.RLock()
+	defer fake.commentsMutex.RUnlock()
+	return fake.commentsReturns.result1, fake.commentsReturns.result2
+}
+
+// CommentsCallCount returns the number of calls to Comments.
+func (fake *FakeReader) CommentsCallCount() int {
+	fake.commentsMutex.RLock()
+	defer fake.commentsMutex.RUnlock()
+	return len(fake.commentsArgsForCall)
+}
+
+// CommentsArgsForCall returns the arguments of the i-th call to Comments.
+func (fake *FakeReader) CommentsArgsForCall(i int) *geddit.Submission {
+	fake.commentsMutex.RLock()
+	defer fake.commentsMutex.RUnlock()
+	args := fake.commentsArgsForCall[i]
+	return args.arg1
+}
+
+// CommentsReturns sets the values returned by Comments when it has no stub.
+func (fake *FakeReader) CommentsReturns(result1 []*geddit.Comment, result2 error) {
+	fake.commentsMutex.Lock()
+	defer fake.commentsMutex.Unlock()
+	fake.CommentsStub = nil
+	fake.commentsReturns.result1 = result1
+	fake.commentsReturns.result2 = result2
+}
+
+var _ geddit.Reader = new(FakeReader)
@@ -0,0 +1,150 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddittest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+)
+
+// defaultPrefs returns the preferences of an account that never changed
+// them. Only a few of reddit's preferences are modeled; the others are
+// stored as they are set.
+func defaultPrefs() map[string]interface{} {
+	return map[string]interface{}{
+		"lang":                 "en",
+		"over_18":              false,
+		"nightmode":            false,
+		"min_comment_score":    -4,
+		"default_comment_sort": "confidence",
+		"show_flair":           true,
+	}
+}
+
+// prefs serves /api/v1/me/prefs: GET returns the preferences of user and
+// PATCH merges in the ones of the json form value or JSON body.
+func (s *Server) prefs(w http.ResponseWriter, r *http.Request, user string) {
+	if user == "" {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	prefs := s.preferences[user]
+	if prefs == nil {
+		prefs = defaultPrefs()
+		s.preferences[user] = prefs
+	}
+	switch r.Method {
+	case "GET":
+	case "PATCH":
+		body := []byte(r.Form.Get("json"))
+		if len(body) == 0 {
+			body, _ = ioutil.ReadAll(r.Body)
+		}
+		var changes map[string]interface{}
+		if err := json.Unmarshal(body, &changes); err != nil {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+		for k, v := range changes {
+			prefs[k] = v
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+// karma serves /api/v1/me/karma, summing the scores of the links and
+// comments of user per subreddit.
+func (s *Server) karma(w http.ResponseWriter, user string) {
+	if user == "" {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	type subredditKarma struct {
+		Subreddit    string `json:"sr"`
+		LinkKarma    int    `json:"link_karma"`
+		CommentKarma int    `json:"comment_karma"`
+	}
+	bySubreddit := make(map[string]*subredditKarma)
+	get := func(sub string) *subredditKarma {
+		k := bySubreddit[sub]
+		if k == nil {
+			k = &subredditKarma{Subreddit: sub}
+			bySubreddit[sub] = k
+		}
+		return k
+	}
+	for _, l := range s.links {
+		if l.Author == user {
+			get(l.Subreddit).LinkKarma += l.Score
+		}
+	}
+	for _, c := range s.comments {
+		if l, ok := s.links[c.LinkID]; ok && c.Author == user {
+			get(l.Subreddit).CommentKarma += c.Score
+		}
+	}
+	karma := []*subredditKarma{}
+	for _, k := range bySubreddit {
+		karma = append(karma, k)
+	}
+	sort.Slice(karma, func(i, j int) bool { return karma[i].Subreddit < karma[j].Subreddit })
+	writeJSON(w, http.StatusOK, thing{"KarmaList", karma})
+}
+
+// trophies serves the TrophyList of the named account.
+func (s *Server) trophies(w http.ResponseWriter, name string) {
+	a, ok := s.accounts[name]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	trophies := []thing{}
+	for _, t := range a.Trophies {
+		trophies = append(trophies, thing{"t6", map[string]interface{}{
+			"id":          nil,
+			"award_id":    nil,
+			"name":        t,
+			"description": nil,
+			"url":         nil,
+			"icon_40":     "",
+			"icon_70":     "",
+			"granted_at":  nil,
+		}})
+	}
+	writeJSON(w, http.StatusOK, thing{"TrophyList", map[string]interface{}{"trophies": trophies}})
+}
+
+// userList writes names as an unpaginated UserList.
+func (s *Server) userList(w http.ResponseWriter, names []string) {
+	children := []map[string]interface{}{}
+	for _, name := range names {
+		children = append(children, map[string]interface{}{
+			"name":   name,
+			"id":     "t2_" + name,
+			"rel_id": "r9_" + name,
+			"date":   unix(s.now()),
+		})
+	}
+	writeJSON(w, http.StatusOK, thing{"UserList", map[string]interface{}{"children": children}})
+}
+
+// block serves /api/block_user, blocking the account named by the name
+// form value for user.
+func (s *Server) block(w http.ResponseWriter, r *http.Request, user string) {
+	name := r.Form.Get("name")
+	if _, ok := s.accounts[name]; !ok {
+		writeAPI(w, r, nil, [][]string{{"USER_DOESNT_EXIST", "that user doesn't exist", "name"}})
+		return
+	}
+	if !contains(s.blocked[user], name) {
+		s.blocked[user] = append(s.blocked[user], name)
+	}
+	writeAPI(w, r, nil, nil)
+}
@@ -0,0 +1,304 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddittest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Doer performs HTTP requests, like geddit.Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Matching selects how a replaying Cassette matches requests to recorded
+// interactions.
+type Matching int
+
+const (
+	// Strict requires requests to come in the recorded order, each with
+	// the recorded method, URL and body.
+	Strict Matching = iota
+	// Lenient answers a request with the first unused interaction with
+	// the same method and URL path, ignoring the query string, the body
+	// and the order; once all are used they are replayed again.
+	Lenient
+)
+
+// redacted replaces scrubbed secrets.
+const redacted = "REDACTED"
+
+// secretParams are the query, form and JSON fields scrubbed from
+// cassettes.
+var secretParams = map[string]bool{
+	"access_token":  true,
+	"client_secret": true,
+	"code":          true,
+	"cookie":        true,
+	"curpass":       true,
+	"modhash":       true,
+	"passwd":        true,
+	"password":      true,
+	"refresh_token": true,
+	"token":         true,
+	"uh":            true,
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status int         `json:"status"`
+		Header http.Header `json:"header"`
+		Body   string      `json:"body"`
+	} `json:"response"`
+
+	used bool
+}
+
+// Cassette records HTTP interactions to a file and replays them. It is a
+// Doer, so it can be given to a session with SetHTTPClient or
+// WithHTTPClient. Request headers, and with them the Authorization header
+// and cookies, are not recorded; passwords, tokens, modhashes and the
+// reddit_session cookie are scrubbed from the rest before it is stored.
+type Cassette struct {
+	path     string
+	next     Doer
+	matching Matching
+
+	mu           sync.Mutex
+	interactions []*Interaction
+}
+
+// RecordCassette returns a Cassette sending requests through next and
+// recording them. Save writes them to path.
+func RecordCassette(path string, next Doer) *Cassette {
+	return &Cassette{path: path, next: next}
+}
+
+// LoadCassette reads a cassette from path to replay it.
+func LoadCassette(path string, matching Matching) (*Cassette, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{path: path, matching: matching}
+	if err = json.Unmarshal(b, &c.interactions); err != nil {
+		return nil, fmt.Errorf("geddittest: reading cassette %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the recorded interactions to the cassette's file.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, append(b, '\n'), 0600)
+}
+
+// Do records or replays req.
+func (c *Cassette) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if c.next == nil {
+		return c.replay(req, body)
+	}
+	return c.record(req, body)
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := c.next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	in := &Interaction{}
+	in.Request.Method = req.Method
+	in.Request.URL = scrubURL(req.URL)
+	in.Request.Body = scrubBody(body, req.Header.Get("Content-Type"))
+	in.Response.Status = resp.StatusCode
+	in.Response.Header = scrubHeader(resp.Header)
+	in.Response.Body = scrubBody(respBody, resp.Header.Get("Content-Type"))
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, in)
+	c.mu.Unlock()
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	method := req.Method
+	u := scrubURL(req.URL)
+	b := scrubBody(body, req.Header.Get("Content-Type"))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var match *Interaction
+	if c.matching == Strict {
+		for _, in := range c.interactions {
+			if in.used {
+				continue
+			}
+			if in.Request.Method != method || in.Request.URL != u || in.Request.Body != b {
+				return nil, fmt.Errorf("geddittest: got %s %s, want %s %s next", method, u, in.Request.Method, in.Request.URL)
+			}
+			match = in
+			break
+		}
+	} else {
+		match = c.lenientMatch(method, req.URL.Path, false)
+		if match == nil {
+			match = c.lenientMatch(method, req.URL.Path, true)
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("geddittest: no recorded interaction for %s %s", method, u)
+	}
+	match.used = true
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", match.Response.Status, http.StatusText(match.Response.Status)),
+		StatusCode:    match.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        match.Response.Header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(match.Response.Body)),
+		ContentLength: int64(len(match.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// lenientMatch returns the first interaction for method and urlpath,
+// skipping used ones unless reuse is set. c.mu must be held.
+func (c *Cassette) lenientMatch(method, urlpath string, reuse bool) *Interaction {
+	for _, in := range c.interactions {
+		if in.used && !reuse {
+			continue
+		}
+		u, err := url.Parse(in.Request.URL)
+		if err == nil && in.Request.Method == method && u.Path == urlpath {
+			return in
+		}
+	}
+	return nil
+}
+
+// scrubURL returns u with its secret query parameters and user info
+// redacted.
+func scrubURL(u *url.URL) string {
+	cp := *u
+	cp.User = nil
+	if cp.RawQuery != "" {
+		q := cp.Query()
+		scrubValues(q)
+		cp.RawQuery = q.Encode()
+	}
+	return cp.String()
+}
+
+func scrubValues(v url.Values) {
+	for key := range v {
+		if secretParams[key] {
+			v.Set(key, redacted)
+		}
+	}
+}
+
+// scrubBody redacts the secrets of a form or JSON body.
+func scrubBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		v, err := url.ParseQuery(string(body))
+		if err != nil {
+			return string(body)
+		}
+		scrubValues(v)
+		return v.Encode()
+	case strings.Contains(contentType, "json"):
+		var doc interface{}
+		if json.Unmarshal(body, &doc) != nil || !scrubJSON(doc) {
+			return string(body)
+		}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return string(body)
+		}
+		return string(b)
+	}
+	return string(body)
+}
+
+// scrubJSON redacts secret fields of a decoded JSON document in place and
+// reports whether it changed anything.
+func scrubJSON(doc interface{}) bool {
+	changed := false
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if _, ok := val.(string); ok && secretParams[key] {
+				v[key] = redacted
+				changed = true
+			} else if scrubJSON(val) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, val := range v {
+			if scrubJSON(val) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// scrubHeader returns a copy of a response header with session cookies
+// redacted.
+func scrubHeader(h http.Header) http.Header {
+	h = h.Clone()
+	cookies := h.Values("Set-Cookie")
+	h.Del("Set-Cookie")
+	for _, c := range cookies {
+		if strings.HasPrefix(c, "reddit_session=") {
+			c = "reddit_session=" + redacted + c[strings.IndexAny(c+";", ";"):]
+		}
+		h.Add("Set-Cookie", c)
+	}
+	return h
+}
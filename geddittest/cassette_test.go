@@ -0,0 +1,87 @@
+package geddittest_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jzelinskie/geddit"
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// recordCassette records a session authenticating and fetching its
+// identity, and returns the cassette's path.
+func recordCassette(t *testing.T) string {
+	srv := newServer(t)
+	path := filepath.Join(t.TempDir(), "me.json")
+	c := geddittest.RecordCassette(path, srv.Client())
+
+	s, err := geddit.NewOAuthSession(geddittest.Username, geddittest.Password, "geddittest",
+		geddittest.ClientID, geddittest.ClientSecret, geddit.WithHTTPClient(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func replaySession(t *testing.T, path string, matching geddittest.Matching) *geddit.OAuthSession {
+	c, err := geddittest.LoadCassette(path, matching)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := geddit.NewOAuthSession(geddittest.Username, geddittest.Password, "geddittest",
+		geddittest.ClientID, geddittest.ClientSecret, geddit.WithHTTPClient(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCassetteScrubsSecrets(t *testing.T) {
+	b, err := ioutil.ReadFile(recordCassette(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(b)
+	for _, secret := range []string{geddittest.Password, geddittest.ClientSecret} {
+		if strings.Contains(cassette, secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, cassette)
+		}
+	}
+	if !strings.Contains(cassette, `password=REDACTED`) || !strings.Contains(cassette, `\"access_token\":\"REDACTED\"`) {
+		t.Errorf("cassette was not scrubbed:\n%s", cassette)
+	}
+}
+
+func TestCassetteReplay(t *testing.T) {
+	path := recordCassette(t)
+
+	s := replaySession(t, path, geddittest.Strict)
+	me, err := s.Me()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Name != geddittest.Username {
+		t.Errorf("got %s, want %s", me.Name, geddittest.Username)
+	}
+	if _, err = s.Me(); err == nil {
+		t.Error("strict replay answered a request that was not recorded")
+	}
+
+	s = replaySession(t, path, geddittest.Lenient)
+	for i := 0; i < 2; i++ {
+		if _, err = s.Me(); err != nil {
+			t.Fatalf("lenient replay %d: %v", i, err)
+		}
+	}
+	if _, err = s.User("someone"); err == nil {
+		t.Error("lenient replay answered a request for another path")
+	}
+}
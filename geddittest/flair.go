@@ -0,0 +1,119 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddittest
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// flairCSVLimit is the most rows the flaircsv endpoint accepts per call.
+const flairCSVLimit = 100
+
+// flairTextLimit is the longest flair text reddit accepts.
+const flairTextLimit = 64
+
+// userFlair is the flair of a user in a subreddit.
+type userFlair struct {
+	Text     string
+	CSSClass string
+}
+
+// flairCSV sets the flair of the users of the user,text,css_class rows of
+// the flair_csv form value, answering with the outcome of every row like
+// reddit: rows of unknown users or with a text too long are skipped with
+// an error, and rows with no text nor class remove the user's flair.
+func (s *Server) flairCSV(w http.ResponseWriter, r *http.Request, sub, user string) {
+	if !s.isModerator(sub, user) {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	rows, err := csv.NewReader(strings.NewReader(r.Form.Get("flair_csv"))).ReadAll()
+	if err != nil || len(rows) > flairCSVLimit {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	if s.flairs[sub] == nil {
+		s.flairs[sub] = make(map[string]*userFlair)
+	}
+
+	results := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		for len(row) < 3 {
+			row = append(row, "")
+		}
+		name, text, class := row[0], row[1], row[2]
+		result := map[string]interface{}{
+			"ok":       false,
+			"status":   "skipped",
+			"errors":   map[string]string{},
+			"warnings": map[string]string{},
+		}
+		switch {
+		case s.accounts[name] == nil:
+			result["errors"] = map[string]string{"user": "unable to resolve user `" + name + "', ignoring"}
+		case len(text) > flairTextLimit:
+			result["errors"] = map[string]string{"text": "flair text is longer than " + strconv.Itoa(flairTextLimit) + " characters"}
+		case text == "" && class == "":
+			delete(s.flairs[sub], name)
+			result["ok"], result["status"] = true, "removed flair for user "+name
+		default:
+			s.flairs[sub][name] = &userFlair{Text: text, CSSClass: class}
+			result["ok"], result["status"] = true, "added flair for user "+name
+		}
+		results = append(results, result)
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// flairList serves the user flairs of a subreddit, sorted by user, or the
+// flair of the user given in the name parameter.
+func (s *Server) flairList(w http.ResponseWriter, r *http.Request, sub, user string) {
+	if !s.isModerator(sub, user) {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	var names []string
+	for name := range s.flairs[sub] {
+		if n := r.Form.Get("name"); n == "" || n == name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if after := r.Form.Get("after"); after != "" {
+		i := sort.SearchStrings(names, after)
+		if i < len(names) && names[i] == after {
+			i++
+		}
+		names = names[i:]
+	}
+	limit, err := strconv.Atoi(r.Form.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 1000
+	}
+	var next string
+	if len(names) > limit {
+		names = names[:limit]
+		next = names[limit-1]
+	}
+
+	users := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		f := s.flairs[sub][name]
+		users[i] = map[string]interface{}{
+			"user":            name,
+			"flair_text":      f.Text,
+			"flair_css_class": f.CSSClass,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"users": users,
+		"next":  nullable(next),
+		"prev":  nil,
+	})
+}
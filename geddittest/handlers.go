@@ -0,0 +1,1181 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddittest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// thing is a reddit kind/data wrapper.
+type thing struct {
+	Kind string      `json:"kind"`
+	Data interface{} `json:"data"`
+}
+
+// relationshipPaths maps the /r/{sub}/about/ pages to the relationships
+// they list.
+var relationshipPaths = map[string]string{
+	"banned":           "banned",
+	"muted":            "muted",
+	"contributors":     "contributor",
+	"moderators":       "moderator",
+	"wikibanned":       "wikibanned",
+	"wikicontributors": "wikicontributor",
+}
+
+var sorts = map[string]bool{
+	"hot":           true,
+	"best":          true,
+	"new":           true,
+	"top":           true,
+	"rising":        true,
+	"controversial": true,
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	p := strings.TrimSuffix(r.URL.Path, "/")
+	p = strings.TrimSuffix(strings.TrimSuffix(p, ".json"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	if s.rateLimited(w.Header()) {
+		w.Header().Set("Retry-After", w.Header().Get("X-Ratelimit-Reset"))
+		writeError(w, http.StatusTooManyRequests)
+		return
+	}
+	if f := s.fault(r.Method, p); f != nil {
+		if f.Errors != nil {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"json": map[string]interface{}{"errors": f.Errors},
+			})
+			return
+		}
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Seconds())))
+		}
+		writeError(w, f.Status)
+		return
+	}
+
+	segs := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if len(segs) >= 2 && segs[0] == "api" && (segs[1] == "login" || p == "/api/v1/access_token" || p == "/api/v1/revoke_token") {
+		switch segs[1] {
+		case "login":
+			s.login(w, r)
+		case "v1":
+			if segs[2] == "access_token" {
+				s.accessToken(w, r)
+			} else {
+				s.revokeToken(w, r)
+			}
+		}
+		return
+	}
+
+	user, ok := s.authenticate(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+	if r.Method != "GET" {
+		s.route(w, r, segs, user)
+		return
+	}
+	ew := &etagWriter{ResponseWriter: w}
+	s.route(ew, r, segs, user)
+	ew.flush(r)
+}
+
+// etagWriter buffers the response to a GET request to answer conditional
+// requests, like reddit does: successful responses get an ETag, and become
+// a 304 Not Modified when it matches the request's If-None-Match.
+type etagWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *etagWriter) flush(r *http.Request) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status == http.StatusOK {
+		sum := sha256.Sum256(w.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// authenticate returns the user a request is made by, from its bearer token
+// or session cookie. It returns false if the credentials are invalid.
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		fields := strings.Fields(auth)
+		if len(fields) != 2 || !strings.EqualFold(fields[0], "bearer") {
+			return "", false
+		}
+		t, ok := s.tokens[fields[1]]
+		if !ok || s.now().After(t.expires) {
+			return "", false
+		}
+		return t.user, true
+	}
+	if c, err := r.Cookie("reddit_session"); err == nil {
+		user, ok := s.cookies[c.Value]
+		return user, ok
+	}
+	return "", true
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request, segs []string, user string) {
+	n := len(segs)
+	switch {
+	case segs[0] == "api" && n == 2 && segs[1] == "me":
+		if user == "" {
+			writeError(w, http.StatusForbidden)
+			return
+		}
+		writeJSON(w, http.StatusOK, thing{"t2", s.accountJSON(s.accounts[user])})
+	case segs[0] == "api" && n == 3 && segs[1] == "v1" && segs[2] == "me":
+		if user == "" {
+			writeError(w, http.StatusForbidden)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.accountJSON(s.accounts[user]))
+	case segs[0] == "api" && n == 4 && segs[1] == "v1" && segs[2] == "me" && segs[3] == "prefs":
+		s.prefs(w, r, user)
+	case segs[0] == "api" && n == 4 && segs[1] == "v1" && segs[2] == "me" && segs[3] == "karma":
+		s.karma(w, user)
+	case segs[0] == "api" && n == 4 && segs[1] == "v1" && segs[2] == "me" && segs[3] == "trophies":
+		if user == "" {
+			writeError(w, http.StatusForbidden)
+			return
+		}
+		s.trophies(w, user)
+	case segs[0] == "api" && n == 5 && segs[1] == "v1" && segs[2] == "user" && segs[4] == "trophies":
+		s.trophies(w, segs[3])
+	case segs[0] == "api" && n == 4 && segs[1] == "v1" && segs[2] == "me" && segs[3] == "friends":
+		if user == "" {
+			writeError(w, http.StatusForbidden)
+			return
+		}
+		s.userList(w, s.friends[user])
+	case segs[0] == "prefs" && n == 2 && segs[1] == "blocked":
+		if user == "" {
+			writeError(w, http.StatusForbidden)
+			return
+		}
+		s.userList(w, s.blocked[user])
+	case segs[0] == "api" && n == 5 && segs[1] == "v1" && segs[2] == "me" && segs[3] == "friends":
+		s.meFriend(w, r, segs[4], user)
+	case segs[0] == "api" && n >= 3 && segs[1] == "mod" && segs[2] == "conversations":
+		s.modmail(w, r, segs[3:], user)
+	case segs[0] == "api" && n >= 3 && segs[1] == "multi":
+		s.multiAPI(w, r, segs[2:], user)
+	case segs[0] == "api" && n == 2 && segs[1] == "needs_captcha":
+		// Captchas are not modeled; no account ever needs one.
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Write([]byte("false"))
+	case segs[0] == "api" && n == 2:
+		s.api(w, r, "", segs[1], user)
+	case segs[0] == "r" && n == 5 && segs[2] == "api" && segs[3] == "wiki" && segs[4] == "edit":
+		s.wikiEdit(w, r, segs[1], user)
+	case segs[0] == "r" && n == 4 && segs[2] == "wiki":
+		s.wikiPage(w, r, segs[1], segs[3])
+	case segs[0] == "r" && n == 4 && segs[2] == "api":
+		if _, ok := s.subreddits[segs[1]]; !ok {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		s.api(w, r, segs[1], segs[3], user)
+	case segs[0] == "r" && n >= 4 && segs[2] == "comments":
+		s.commentsPage(w, r, segs[3], user)
+	case segs[0] == "comments" && n >= 2:
+		s.commentsPage(w, r, segs[1], user)
+	case segs[0] == "r" && n == 3 && segs[2] == "about":
+		s.aboutSubreddit(w, segs[1])
+	case segs[0] == "r" && n == 4 && segs[2] == "about" && segs[3] == "log":
+		s.modLog(w, r, segs[1], user)
+	case segs[0] == "r" && n == 4 && segs[2] == "about":
+		s.relationships(w, segs[1], segs[3], user)
+	case segs[0] == "r" && n == 2:
+		s.listing(w, r, segs[1], "hot", user)
+	case segs[0] == "r" && n == 3 && sorts[segs[2]]:
+		s.listing(w, r, segs[1], segs[2], user)
+	case n == 1 && (segs[0] == "" || sorts[segs[0]]):
+		s.listing(w, r, "", segs[0], user)
+	case segs[0] == "user" && n == 5 && segs[2] == "m" && sorts[segs[4]]:
+		s.multiListing(w, r, segs[1], segs[3], segs[4], user)
+	case segs[0] == "user" && n == 3:
+		s.userPage(w, r, segs[1], segs[2], user)
+	case segs[0] == "message" && n == 2:
+		s.messageBox(w, r, segs[1], user)
+	default:
+		writeError(w, http.StatusNotFound)
+	}
+}
+
+// api serves the /api/ endpoints, subreddit-scoped when sub is not empty.
+func (s *Server) api(w http.ResponseWriter, r *http.Request, sub, endpoint, user string) {
+	if endpoint == "morechildren" {
+		s.moreChildren(w, r, user)
+		return
+	}
+	if endpoint == "flairlist" && sub != "" {
+		s.flairList(w, r, sub, user)
+		return
+	}
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	if user == "" {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+
+	switch endpoint {
+	case "vote":
+		s.vote(w, r, user)
+	case "submit":
+		s.submit(w, r, user)
+	case "comment":
+		s.comment(w, r, user)
+	case "del":
+		s.del(w, r, user)
+	case "compose":
+		s.compose(w, r, user)
+	case "read_message", "unread_message":
+		for _, id := range strings.Split(r.Form.Get("id"), ",") {
+			for _, m := range s.messages {
+				if "t4_"+m.ID == id && m.To == user {
+					m.Unread = endpoint == "unread_message"
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, struct{}{})
+	case "approve", "remove":
+		s.moderate(w, r, endpoint == "remove", user)
+	case "friend", "unfriend":
+		s.friend(w, r, sub, endpoint == "friend", user)
+	case "flaircsv":
+		s.flairCSV(w, r, sub, user)
+	case "block_user":
+		s.block(w, r, user)
+	default:
+		writeError(w, http.StatusNotFound)
+	}
+}
+
+func (s *Server) accessToken(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || (len(s.apps) > 0 && s.apps[id] != secret) {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	var user string
+	switch r.Form.Get("grant_type") {
+	case "password":
+		a, ok := s.accounts[r.Form.Get("username")]
+		if !ok || a.Password != r.Form.Get("password") {
+			writeJSON(w, http.StatusOK, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		user = a.Name
+	case "refresh_token":
+		user, ok = s.refresh[r.Form.Get("refresh_token")]
+		if !ok {
+			writeJSON(w, http.StatusOK, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	case "client_credentials":
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	scope := r.Form.Get("scope")
+	if scope == "" {
+		scope = s.scope
+	}
+	access := randomToken()
+	s.tokens[access] = &token{user: user, expires: s.now().Add(s.tokenTTL)}
+	resp := map[string]interface{}{
+		"access_token": access,
+		"token_type":   "bearer",
+		"expires_in":   int(s.tokenTTL.Seconds()),
+		"scope":        scope,
+	}
+	if r.Form.Get("duration") == "permanent" || r.Form.Get("grant_type") == "refresh_token" {
+		refresh := r.Form.Get("refresh_token")
+		if refresh == "" {
+			refresh = randomToken()
+			s.refresh[refresh] = user
+		}
+		resp["refresh_token"] = refresh
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) revokeToken(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := r.BasicAuth(); !ok {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+	t := r.Form.Get("token")
+	delete(s.tokens, t)
+	delete(s.refresh, t)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.accounts[r.Form.Get("user")]
+	if !ok || a.Password != r.Form.Get("passwd") {
+		writeAPI(w, r, nil, [][]string{{"WRONG_PASSWORD", "wrong password", "passwd"}})
+		return
+	}
+	cookie := randomToken()
+	s.cookies[cookie] = a.Name
+	http.SetCookie(w, &http.Cookie{Name: "reddit_session", Value: cookie, Path: "/"})
+	writeAPI(w, r, map[string]string{"modhash": randomToken(), "cookie": cookie}, nil)
+}
+
+func (s *Server) listing(w http.ResponseWriter, r *http.Request, sub, order, user string) {
+	if sub != "" {
+		if _, ok := s.subreddits[sub]; !ok {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+	}
+	var links []*Link
+	for _, id := range s.linkOrder {
+		l := s.links[id]
+		if (sub == "" || l.Subreddit == sub) && (!l.Removed || s.isModerator(l.Subreddit, user)) {
+			links = append(links, l)
+		}
+	}
+	sortLinks(links, order)
+
+	things := make([]thing, len(links))
+	for i, l := range links {
+		things[i] = thing{"t3", s.linkJSON(l, user)}
+	}
+	writeListing(w, r, things)
+}
+
+func sortLinks(links []*Link, order string) {
+	sort.SliceStable(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		switch order {
+		case "new", "rising":
+			return a.Created.After(b.Created)
+		case "controversial":
+			return a.Score < b.Score
+		default:
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			return a.Created.After(b.Created)
+		}
+	})
+}
+
+func (s *Server) userPage(w http.ResponseWriter, r *http.Request, name, page, user string) {
+	a, ok := s.accounts[name]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	var things []thing
+	switch page {
+	case "about":
+		writeJSON(w, http.StatusOK, thing{"t2", s.accountJSON(a)})
+		return
+	case "submitted", "overview":
+		for _, id := range s.linkOrder {
+			if l := s.links[id]; l.Author == name && !l.Removed {
+				things = append(things, thing{"t3", s.linkJSON(l, user)})
+			}
+		}
+		if page == "submitted" {
+			break
+		}
+		fallthrough
+	case "comments":
+		for _, id := range s.commentOrder {
+			if c := s.comments[id]; c.Author == name && !c.Removed {
+				things = append(things, thing{"t1", s.commentJSON(c, user, nil)})
+			}
+		}
+	case "upvoted", "downvoted":
+		// Private to the account, like saved and hidden.
+		if user != name {
+			writeError(w, http.StatusForbidden)
+			return
+		}
+		dir := 1
+		if page == "downvoted" {
+			dir = -1
+		}
+		for _, id := range s.linkOrder {
+			if l := s.links[id]; s.votes[name+" t3_"+id] == dir && !l.Removed {
+				things = append(things, thing{"t3", s.linkJSON(l, user)})
+			}
+		}
+	case "saved", "hidden":
+		// Private to the account; saving and hiding are not modeled, so
+		// they are always empty.
+		if user != name {
+			writeError(w, http.StatusForbidden)
+			return
+		}
+	default:
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	writeListing(w, r, things)
+}
+
+func (s *Server) commentsPage(w http.ResponseWriter, r *http.Request, id, user string) {
+	l, ok := s.links[id]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, []interface{}{
+		listingJSON([]thing{{"t3", s.linkJSON(l, user)}}, ""),
+		listingJSON(s.replies("t3_"+id, user, s.commentTree()), ""),
+	})
+}
+
+// commentTree returns the comments of the model by parent fullname, in
+// order.
+func (s *Server) commentTree() map[string][]*Comment {
+	tree := make(map[string][]*Comment)
+	for _, id := range s.commentOrder {
+		c := s.comments[id]
+		tree[c.ParentID] = append(tree[c.ParentID], c)
+	}
+	return tree
+}
+
+// replies returns the comment tree under parent, with collapsed comments
+// gathered into a trailing "more" stub.
+func (s *Server) replies(parent, user string, tree map[string][]*Comment) []thing {
+	var things []thing
+	var more []string
+	for _, c := range tree[parent] {
+		if c.Collapsed {
+			more = append(more, c.ID)
+			continue
+		}
+		things = append(things, thing{"t1", s.commentJSON(c, user, tree)})
+	}
+	if len(more) > 0 {
+		things = append(things, thing{"more", map[string]interface{}{
+			"count":     len(more),
+			"children":  more,
+			"parent_id": parent,
+			"id":        more[0],
+			"name":      "t1_" + more[0],
+		}})
+	}
+	return things
+}
+
+func (s *Server) moreChildren(w http.ResponseWriter, r *http.Request, user string) {
+	linkID := strings.TrimPrefix(r.Form.Get("link_id"), "t3_")
+	if _, ok := s.links[linkID]; !ok {
+		writeAPI(w, r, nil, [][]string{{"INVALID_OPTION", "that link doesn't exist", "link_id"}})
+		return
+	}
+	var things []thing
+	var add func(c *Comment)
+	add = func(c *Comment) {
+		things = append(things, thing{"t1", s.commentJSON(c, user, nil)})
+		for _, id := range s.commentOrder {
+			if child := s.comments[id]; child.ParentID == "t1_"+c.ID {
+				add(child)
+			}
+		}
+	}
+	for _, id := range strings.Split(r.Form.Get("children"), ",") {
+		if c, ok := s.comments[id]; ok && c.LinkID == linkID {
+			add(c)
+		}
+	}
+	if things == nil {
+		things = []thing{}
+	}
+	r.Form.Set("api_type", "json")
+	writeAPI(w, r, map[string]interface{}{"things": things}, nil)
+}
+
+func (s *Server) aboutSubreddit(w http.ResponseWriter, name string) {
+	sr, ok := s.subreddits[name]
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, thing{"t5", map[string]interface{}{
+		"display_name":       sr.Name,
+		"title":              sr.Title,
+		"description":        sr.Description,
+		"public_description": sr.Description,
+		"url":                "/r/" + sr.Name + "/",
+		"name":               "t5_" + sr.Name,
+		"id":                 sr.Name,
+		"subscribers":        sr.Subscribers,
+		"created_utc":        unix(sr.Created),
+		"over18":             false,
+	}})
+}
+
+func (s *Server) relationships(w http.ResponseWriter, sub, page, user string) {
+	sr, ok := s.subreddits[sub]
+	rel, known := relationshipPaths[page]
+	if !ok || !known {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	if rel != "moderator" && !s.isModerator(sub, user) {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	children := []map[string]interface{}{}
+	for _, name := range sr.Relationships[rel] {
+		children = append(children, map[string]interface{}{
+			"name":   name,
+			"id":     "t2_" + name,
+			"rel_id": "rb_" + name,
+			"date":   unix(sr.Created),
+			"note":   "",
+		})
+	}
+	writeJSON(w, http.StatusOK, thing{"UserList", map[string]interface{}{
+		"children": children,
+		"after":    nil,
+		"before":   nil,
+	}})
+}
+
+func (s *Server) messageBox(w http.ResponseWriter, r *http.Request, box, user string) {
+	if user == "" {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	var things []thing
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		m := s.messages[i]
+		var match bool
+		switch box {
+		case "inbox", "messages":
+			match = m.To == user
+		case "unread":
+			match = m.To == user && m.Unread
+		case "sent":
+			match = m.From == user
+		default:
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		if match {
+			things = append(things, thing{"t4", messageJSON(m)})
+		}
+	}
+	writeListing(w, r, things)
+}
+
+func (s *Server) vote(w http.ResponseWriter, r *http.Request, user string) {
+	id := r.Form.Get("id")
+	dir, err := strconv.Atoi(r.Form.Get("dir"))
+	if err != nil || dir < -1 || dir > 1 {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	score := s.score(id)
+	if score == nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	key := user + " " + id
+	*score += dir - s.votes[key]
+	s.votes[key] = dir
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// score returns a pointer to the score of a thing, or nil if there is no
+// such thing.
+func (s *Server) score(fullname string) *int {
+	switch {
+	case strings.HasPrefix(fullname, "t3_"):
+		if l, ok := s.links[fullname[3:]]; ok {
+			return &l.Score
+		}
+	case strings.HasPrefix(fullname, "t1_"):
+		if c, ok := s.comments[fullname[3:]]; ok {
+			return &c.Score
+		}
+	}
+	return nil
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request, user string) {
+	sr, ok := s.subreddits[r.Form.Get("sr")]
+	switch {
+	case !ok:
+		writeAPI(w, r, nil, [][]string{{"SUBREDDIT_NOEXIST", "that subreddit doesn't exist", "sr"}})
+		return
+	case contains(sr.Relationships["banned"], user):
+		writeAPI(w, r, nil, [][]string{{"SUBREDDIT_NOTALLOWED", "you aren't allowed to post there.", "sr"}})
+		return
+	case r.Form.Get("title") == "":
+		writeAPI(w, r, nil, [][]string{{"NO_TEXT", "we need something here", "title"}})
+		return
+	}
+
+	l := &Link{
+		Subreddit: sr.Name,
+		Author:    user,
+		Title:     r.Form.Get("title"),
+		Score:     1,
+	}
+	if r.Form.Get("kind") == "self" {
+		l.Selftext = r.Form.Get("text")
+	} else {
+		l.URL = r.Form.Get("url")
+	}
+	s.addLink(l)
+	s.votes[user+" t3_"+l.ID] = 1
+	writeAPI(w, r, map[string]string{
+		"url":  "https://www.reddit.com" + permalink(l),
+		"id":   l.ID,
+		"name": "t3_" + l.ID,
+	}, nil)
+}
+
+func (s *Server) comment(w http.ResponseWriter, r *http.Request, user string) {
+	parent := r.Form.Get("thing_id")
+	var linkID string
+	switch {
+	case strings.HasPrefix(parent, "t3_") && s.links[parent[3:]] != nil:
+		linkID = parent[3:]
+	case strings.HasPrefix(parent, "t1_") && s.comments[parent[3:]] != nil:
+		linkID = s.comments[parent[3:]].LinkID
+	default:
+		writeAPI(w, r, nil, [][]string{{"INVALID_OPTION", "that thing doesn't exist", "thing_id"}})
+		return
+	}
+	if r.Form.Get("text") == "" {
+		writeAPI(w, r, nil, [][]string{{"NO_TEXT", "we need something here", "text"}})
+		return
+	}
+
+	c := &Comment{
+		LinkID:   linkID,
+		ParentID: parent,
+		Author:   user,
+		Body:     r.Form.Get("text"),
+		Score:    1,
+	}
+	s.addComment(c)
+	s.votes[user+" t1_"+c.ID] = 1
+	writeAPI(w, r, map[string]interface{}{
+		"things": []thing{{"t1", s.commentJSON(c, user, nil)}},
+	}, nil)
+}
+
+func (s *Server) del(w http.ResponseWriter, r *http.Request, user string) {
+	id := r.Form.Get("id")
+	switch {
+	case strings.HasPrefix(id, "t3_"):
+		if l, ok := s.links[id[3:]]; ok && l.Author == user {
+			l.Author = "[deleted]"
+			l.Selftext = "[deleted]"
+		}
+	case strings.HasPrefix(id, "t1_"):
+		if c, ok := s.comments[id[3:]]; ok && c.Author == user {
+			c.Author = "[deleted]"
+			c.Body = "[deleted]"
+		}
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) compose(w http.ResponseWriter, r *http.Request, user string) {
+	to := r.Form.Get("to")
+	if _, ok := s.accounts[to]; !ok {
+		writeAPI(w, r, nil, [][]string{{"USER_DOESNT_EXIST", "that user doesn't exist", "to"}})
+		return
+	}
+	if r.Form.Get("subject") == "" {
+		writeAPI(w, r, nil, [][]string{{"NO_SUBJECT", "please enter a subject", "subject"}})
+		return
+	}
+	s.addMessage(&Message{
+		From:    user,
+		To:      to,
+		Subject: r.Form.Get("subject"),
+		Body:    r.Form.Get("text"),
+		Unread:  true,
+	})
+	writeAPI(w, r, nil, nil)
+}
+
+func (s *Server) moderate(w http.ResponseWriter, r *http.Request, removing bool, user string) {
+	id := r.Form.Get("id")
+	var sub string
+	var removed *bool
+	var removedBy, approvedBy *string
+	switch {
+	case strings.HasPrefix(id, "t3_") && s.links[id[3:]] != nil:
+		l := s.links[id[3:]]
+		sub, removed, removedBy, approvedBy = l.Subreddit, &l.Removed, &l.RemovedBy, &l.ApprovedBy
+	case strings.HasPrefix(id, "t1_") && s.comments[id[3:]] != nil:
+		c := s.comments[id[3:]]
+		sub, removed, removedBy, approvedBy = s.links[c.LinkID].Subreddit, &c.Removed, &c.RemovedBy, &c.ApprovedBy
+	default:
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	if !s.isModerator(sub, user) {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	*removed = removing
+	if removing {
+		*removedBy, *approvedBy = user, ""
+	} else {
+		*removedBy, *approvedBy = "", user
+	}
+	action := "approve"
+	if removing {
+		action = "remove"
+	}
+	if strings.HasPrefix(id, "t3_") {
+		action += "link"
+	} else {
+		action += "comment"
+	}
+	s.modActions = append(s.modActions, &modAction{
+		ID:        "ModAction_" + s.newID(),
+		Subreddit: sub,
+		Action:    action,
+		Mod:       user,
+		Target:    id,
+		Created:   s.now(),
+	})
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// modLog serves a subreddit's moderation log, newest first, of the actions
+// of the type given in the query if any.
+func (s *Server) modLog(w http.ResponseWriter, r *http.Request, sub, user string) {
+	if _, ok := s.subreddits[sub]; !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	if !s.isModerator(sub, user) {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	things := []thing{}
+	for i := len(s.modActions) - 1; i >= 0; i-- {
+		a := s.modActions[i]
+		if a.Subreddit != sub || (r.Form.Get("type") != "" && a.Action != r.Form.Get("type")) {
+			continue
+		}
+		things = append(things, thing{"modaction", map[string]interface{}{
+			"id":              a.ID,
+			"action":          a.Action,
+			"mod":             a.Mod,
+			"target_fullname": a.Target,
+			"subreddit":       a.Subreddit,
+			"details":         nil,
+			"description":     nil,
+			"created_utc":     unix(a.Created),
+		}})
+	}
+	writeListing(w, r, things)
+}
+
+func (s *Server) friend(w http.ResponseWriter, r *http.Request, sub string, adding bool, user string) {
+	name := r.Form.Get("name")
+	rel := r.Form.Get("type")
+	if _, ok := s.accounts[name]; !ok {
+		writeAPI(w, r, nil, [][]string{{"USER_DOESNT_EXIST", "that user doesn't exist", "name"}})
+		return
+	}
+	if sub == "" {
+		// Like reddit, only cookie sessions may befriend users here; OAuth
+		// clients must use /api/v1/me/friends.
+		if rel != "friend" || r.Header.Get("Authorization") != "" {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+		if adding {
+			if !contains(s.friends[user], name) {
+				s.friends[user] = append(s.friends[user], name)
+			}
+		} else {
+			s.friends[user] = remove(s.friends[user], name)
+		}
+		writeAPI(w, r, nil, nil)
+		return
+	}
+	if !s.isModerator(sub, user) {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	sr := s.subreddits[sub]
+	if adding {
+		if !contains(sr.Relationships[rel], name) {
+			sr.Relationships[rel] = append(sr.Relationships[rel], name)
+		}
+	} else {
+		sr.Relationships[rel] = remove(sr.Relationships[rel], name)
+	}
+	writeAPI(w, r, nil, nil)
+}
+
+// meFriend serves /api/v1/me/friends/{name}: PUT befriends the user, with
+// a json form value or a JSON body naming them, and DELETE unfriends them.
+func (s *Server) meFriend(w http.ResponseWriter, r *http.Request, name, user string) {
+	if user == "" {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	if _, ok := s.accounts[name]; !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"fields": []string{"name"}, "explanation": "that user doesn't exist", "reason": "USER_DOESNT_EXIST",
+		})
+		return
+	}
+	friend := map[string]interface{}{"name": name, "id": "t2_" + name, "date": unix(s.now())}
+	switch r.Method {
+	case "GET":
+		if !contains(s.friends[user], name) {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, friend)
+	case "PUT":
+		body := []byte(r.Form.Get("json"))
+		if len(body) == 0 {
+			body, _ = ioutil.ReadAll(r.Body)
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil || req.Name != name {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+		if !contains(s.friends[user], name) {
+			s.friends[user] = append(s.friends[user], name)
+		}
+		writeJSON(w, http.StatusOK, friend)
+	case "DELETE":
+		if !contains(s.friends[user], name) {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		s.friends[user] = remove(s.friends[user], name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) accountJSON(a *Account) map[string]interface{} {
+	inbox := 0
+	for _, m := range s.messages {
+		if m.To == a.Name && m.Unread {
+			inbox++
+		}
+	}
+	return map[string]interface{}{
+		"name":               a.Name,
+		"id":                 a.Name,
+		"link_karma":         a.LinkKarma,
+		"comment_karma":      a.CommentKarma,
+		"created_utc":        unix(a.Created),
+		"has_mail":           inbox > 0,
+		"inbox_count":        inbox,
+		"is_gold":            false,
+		"is_mod":             s.moderatesAny(a.Name),
+		"verified":           true,
+		"has_verified_email": true,
+	}
+}
+
+func (s *Server) moderatesAny(user string) bool {
+	for _, sr := range s.subreddits {
+		if contains(sr.Relationships["moderator"], user) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) linkJSON(l *Link, user string) map[string]interface{} {
+	numComments := 0
+	for _, c := range s.comments {
+		if c.LinkID == l.ID {
+			numComments++
+		}
+	}
+	u := l.URL
+	if l.URL == "" {
+		u = "https://www.reddit.com" + permalink(l)
+	}
+	return map[string]interface{}{
+		"id":            l.ID,
+		"name":          "t3_" + l.ID,
+		"author":        l.Author,
+		"title":         l.Title,
+		"url":           u,
+		"domain":        domain(l),
+		"subreddit":     l.Subreddit,
+		"subreddit_id":  "t5_" + l.Subreddit,
+		"permalink":     permalink(l),
+		"selftext":      l.Selftext,
+		"selftext_html": nil,
+		"thumbnail":     "",
+		"created_utc":   unix(l.Created),
+		"num_comments":  numComments,
+		"score":         l.Score,
+		"ups":           l.Score,
+		"over_18":       l.NSFW,
+		"is_self":       l.URL == "",
+		"clicked":       false,
+		"saved":         false,
+		"stickied":      false,
+		"likes":         s.likes(user, "t3_"+l.ID),
+		"banned_by":     nullable(l.RemovedBy),
+		"approved_by":   nullable(l.ApprovedBy),
+	}
+}
+
+// commentJSON returns the JSON form of a comment, with its replies taken
+// from tree unless it is nil.
+func (s *Server) commentJSON(c *Comment, user string, tree map[string][]*Comment) map[string]interface{} {
+	var replies interface{} = ""
+	if tree != nil {
+		if children := s.replies("t1_"+c.ID, user, tree); len(children) > 0 {
+			replies = listingJSON(children, "")
+		}
+	}
+	return map[string]interface{}{
+		"id":                     c.ID,
+		"name":                   "t1_" + c.ID,
+		"author":                 c.Author,
+		"body":                   c.Body,
+		"body_html":              c.Body,
+		"subreddit":              s.links[c.LinkID].Subreddit,
+		"subreddit_id":           "t5_" + s.links[c.LinkID].Subreddit,
+		"link_id":                "t3_" + c.LinkID,
+		"parent_id":              c.ParentID,
+		"ups":                    c.Score,
+		"downs":                  0,
+		"score":                  c.Score,
+		"created_utc":            unix(c.Created),
+		"edited":                 false,
+		"likes":                  s.likes(user, "t1_"+c.ID),
+		"banned_by":              nullable(c.RemovedBy),
+		"approved_by":            nullable(c.ApprovedBy),
+		"author_flair_text":      nil,
+		"author_flair_css_class": nil,
+		"replies":                replies,
+	}
+}
+
+func messageJSON(m *Message) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          m.ID,
+		"name":        "t4_" + m.ID,
+		"author":      m.From,
+		"dest":        m.To,
+		"subject":     m.Subject,
+		"body":        m.Body,
+		"body_html":   m.Body,
+		"created_utc": unix(m.Created),
+		"new":         m.Unread,
+		"was_comment": false,
+	}
+}
+
+// likes returns a user's vote on a thing the way reddit does: true, false
+// or null.
+func (s *Server) likes(user, fullname string) interface{} {
+	switch s.votes[user+" "+fullname] {
+	case 1:
+		return true
+	case -1:
+		return false
+	}
+	return nil
+}
+
+func permalink(l *Link) string {
+	return "/r/" + l.Subreddit + "/comments/" + l.ID + "/" + slug(l.Title) + "/"
+}
+
+func slug(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+func domain(l *Link) string {
+	if l.URL == "" {
+		return "self." + l.Subreddit
+	}
+	host := l.URL
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+func nullable(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+func unix(t time.Time) float64 {
+	return float64(t.Unix())
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func listingJSON(things []thing, after string) thing {
+	if things == nil {
+		things = []thing{}
+	}
+	var a interface{}
+	if after != "" {
+		a = after
+	}
+	return thing{"Listing", map[string]interface{}{
+		"children": things,
+		"after":    a,
+		"before":   nil,
+	}}
+}
+
+// writeListing writes a page of things, honoring the limit and after
+// parameters of the request.
+func writeListing(w http.ResponseWriter, r *http.Request, things []thing) {
+	if after := r.Form.Get("after"); after != "" {
+		for i, t := range things {
+			if fullname(t) == after {
+				things = things[i+1:]
+				break
+			}
+		}
+	}
+	limit, err := strconv.Atoi(r.Form.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 25
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	var after string
+	if len(things) > limit {
+		things = things[:limit]
+		after = fullname(things[limit-1])
+	}
+	writeJSON(w, http.StatusOK, listingJSON(things, after))
+}
+
+func fullname(t thing) string {
+	data, _ := t.Data.(map[string]interface{})
+	if name, ok := data["name"].(string); ok {
+		return name
+	}
+	// Mod actions have no fullname; listings page through them by ID.
+	id, _ := data["id"].(string)
+	return id
+}
+
+// writeAPI writes reddit's {"json": {"errors": [...], "data": {...}}}
+// envelope. Like reddit, the errors array is only always present when the
+// request asked for api_type=json.
+func writeAPI(w http.ResponseWriter, r *http.Request, data interface{}, errs [][]string) {
+	env := map[string]interface{}{}
+	if len(errs) > 0 || r.Form.Get("api_type") == "json" {
+		if errs == nil {
+			errs = [][]string{}
+		}
+		env["errors"] = errs
+	}
+	if data != nil && len(errs) == 0 {
+		env["data"] = data
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"json": env})
+}
+
+func writeError(w http.ResponseWriter, status int) {
+	writeJSON(w, status, map[string]interface{}{
+		"message": http.StatusText(status),
+		"error":   status,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	b, _ := json.Marshal(v)
+	w.Write(b)
+}
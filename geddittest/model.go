@@ -0,0 +1,327 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddittest
+
+import (
+	"strconv"
+	"time"
+)
+
+// Account is a reddit user of the fake server. Trophies names the trophies
+// it was awarded.
+type Account struct {
+	Name         string    `json:"name"`
+	Password     string    `json:"password"`
+	LinkKarma    int       `json:"link_karma"`
+	CommentKarma int       `json:"comment_karma"`
+	Created      time.Time `json:"created"`
+	Trophies     []string  `json:"trophies"`
+}
+
+// Subreddit is a subreddit of the fake server. Relationships maps a
+// relationship type as reddit names it, e.g. "banned", "moderator" or
+// "contributor", to the users having it.
+type Subreddit struct {
+	Name          string              `json:"name"`
+	Title         string              `json:"title"`
+	Description   string              `json:"description"`
+	Subscribers   int                 `json:"subscribers"`
+	Created       time.Time           `json:"created"`
+	Relationships map[string][]string `json:"relationships"`
+}
+
+// Link is a submission of the fake server.
+type Link struct {
+	ID         string    `json:"id"`
+	Subreddit  string    `json:"subreddit"`
+	Author     string    `json:"author"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	Selftext   string    `json:"selftext"`
+	Score      int       `json:"score"`
+	Created    time.Time `json:"created"`
+	NSFW       bool      `json:"nsfw"`
+	Removed    bool      `json:"removed"`
+	RemovedBy  string    `json:"removed_by"`
+	ApprovedBy string    `json:"approved_by"`
+}
+
+// modAction is an entry of a subreddit's moderation log.
+type modAction struct {
+	ID        string
+	Subreddit string
+	Action    string
+	Mod       string
+	Target    string
+	Created   time.Time
+}
+
+// Comment is a comment of the fake server. ParentID is the fullname of the
+// link or comment it replies to; it defaults to the link. Collapsed
+// comments are left out of comment trees and served as "more" stubs, to be
+// fetched with /api/morechildren.
+type Comment struct {
+	ID         string    `json:"id"`
+	LinkID     string    `json:"link_id"`
+	ParentID   string    `json:"parent_id"`
+	Author     string    `json:"author"`
+	Body       string    `json:"body"`
+	Score      int       `json:"score"`
+	Created    time.Time `json:"created"`
+	Collapsed  bool      `json:"collapsed"`
+	Removed    bool      `json:"removed"`
+	RemovedBy  string    `json:"removed_by"`
+	ApprovedBy string    `json:"approved_by"`
+}
+
+// Message is a private message of the fake server.
+type Message struct {
+	ID      string    `json:"id"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	Created time.Time `json:"created"`
+	Unread  bool      `json:"unread"`
+}
+
+// Fixtures is the initial state of a fake server. Apps maps OAuth client
+// IDs to their secrets.
+type Fixtures struct {
+	Apps       map[string]string `json:"apps"`
+	Accounts   []*Account        `json:"accounts"`
+	Subreddits []*Subreddit      `json:"subreddits"`
+	Links      []*Link           `json:"links"`
+	Comments   []*Comment        `json:"comments"`
+	Messages   []*Message        `json:"messages"`
+}
+
+// The credentials of the app and users of DefaultFixtures.
+const (
+	ClientID     = "geddittest-client"
+	ClientSecret = "geddittest-secret"
+	Username     = "gopher"
+	Password     = "hunter2"
+	Moderator    = "mod"
+)
+
+// DefaultFixtures returns a small world: the users gopher and mod, the
+// subreddit golang moderated by mod, a few links and a comment thread with
+// a collapsed reply.
+func DefaultFixtures() *Fixtures {
+	epoch := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &Fixtures{
+		Apps: map[string]string{ClientID: ClientSecret},
+		Accounts: []*Account{
+			{Name: Username, Password: Password, LinkKarma: 10, CommentKarma: 20, Created: epoch, Trophies: []string{"Verified Email"}},
+			{Name: Moderator, Password: Password, LinkKarma: 100, CommentKarma: 200, Created: epoch},
+		},
+		Subreddits: []*Subreddit{{
+			Name:          "golang",
+			Title:         "The Go Programming Language",
+			Description:   "Ask questions and post articles about Go.",
+			Subscribers:   1000,
+			Created:       epoch,
+			Relationships: map[string][]string{"moderator": {Moderator}},
+		}},
+		Links: []*Link{
+			{ID: "l1", Subreddit: "golang", Author: Username, Title: "Go 1.6 is released", URL: "https://blog.golang.org/go1.6", Score: 50, Created: epoch.Add(time.Hour)},
+			{ID: "l2", Subreddit: "golang", Author: Moderator, Title: "Weekly questions thread", Selftext: "Ask away.", Score: 5, Created: epoch.Add(2 * time.Hour)},
+		},
+		Comments: []*Comment{
+			{ID: "c1", LinkID: "l1", Author: Moderator, Body: "Great release!", Score: 3, Created: epoch.Add(3 * time.Hour)},
+			{ID: "c2", LinkID: "l1", ParentID: "t1_c1", Author: Username, Body: "Agreed.", Score: 1, Created: epoch.Add(4 * time.Hour)},
+			{ID: "c3", LinkID: "l1", ParentID: "t1_c1", Author: Username, Body: "Also, vendoring.", Score: 1, Created: epoch.Add(5 * time.Hour), Collapsed: true},
+		},
+	}
+}
+
+// Load adds fixtures to the server's model, replacing existing objects with
+// the same name or ID. Objects without an ID get one.
+func (s *Server) Load(f *Fixtures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, secret := range f.Apps {
+		s.apps[id] = secret
+	}
+	for _, a := range f.Accounts {
+		s.accounts[a.Name] = a
+	}
+	for _, sr := range f.Subreddits {
+		if sr.Relationships == nil {
+			sr.Relationships = make(map[string][]string)
+		}
+		s.subreddits[sr.Name] = sr
+	}
+	for _, l := range f.Links {
+		s.addLink(l)
+	}
+	for _, c := range f.Comments {
+		s.addComment(c)
+	}
+	for _, m := range f.Messages {
+		s.addMessage(m)
+	}
+}
+
+// newID returns a fresh base36 ID.
+func (s *Server) newID() string {
+	s.lastID++
+	return strconv.FormatInt(s.lastID, 36)
+}
+
+func (s *Server) addLink(l *Link) {
+	if l.ID == "" {
+		l.ID = s.newID()
+	}
+	if l.Created.IsZero() {
+		l.Created = s.now()
+	}
+	if _, ok := s.links[l.ID]; !ok {
+		s.linkOrder = append(s.linkOrder, l.ID)
+	}
+	s.links[l.ID] = l
+}
+
+func (s *Server) addComment(c *Comment) {
+	if c.ID == "" {
+		c.ID = s.newID()
+	}
+	if c.ParentID == "" {
+		c.ParentID = "t3_" + c.LinkID
+	}
+	if c.Created.IsZero() {
+		c.Created = s.now()
+	}
+	if _, ok := s.comments[c.ID]; !ok {
+		s.commentOrder = append(s.commentOrder, c.ID)
+	}
+	s.comments[c.ID] = c
+}
+
+func (s *Server) addMessage(m *Message) {
+	if m.ID == "" {
+		m.ID = s.newID()
+	}
+	if m.Created.IsZero() {
+		m.Created = s.now()
+	}
+	s.messages = append(s.messages, m)
+}
+
+// AddLink adds a link to the model and returns its ID.
+func (s *Server) AddLink(l *Link) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addLink(l)
+	return l.ID
+}
+
+// AddComment adds a comment to the model and returns its ID.
+func (s *Server) AddComment(c *Comment) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addComment(c)
+	return c.ID
+}
+
+// Link returns a copy of a link of the model.
+func (s *Server) Link(id string) (Link, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.links[id]
+	if !ok {
+		return Link{}, false
+	}
+	return *l, true
+}
+
+// Comment returns a copy of a comment of the model.
+func (s *Server) Comment(id string) (Comment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.comments[id]
+	if !ok {
+		return Comment{}, false
+	}
+	return *c, true
+}
+
+// Links returns copies of the links of a subreddit, or of every link if
+// subreddit is empty, in the order they were added.
+func (s *Server) Links(subreddit string) []Link {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var links []Link
+	for _, id := range s.linkOrder {
+		if l := s.links[id]; subreddit == "" || l.Subreddit == subreddit {
+			links = append(links, *l)
+		}
+	}
+	return links
+}
+
+// Messages returns copies of the messages sent to or by a user.
+func (s *Server) Messages(username string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs []Message
+	for _, m := range s.messages {
+		if m.To == username || m.From == username {
+			msgs = append(msgs, *m)
+		}
+	}
+	return msgs
+}
+
+// Vote returns the vote of a user on a thing: 1, 0 or -1.
+func (s *Server) Vote(username, fullname string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.votes[username+" "+fullname]
+}
+
+// Friends returns the friends of a user.
+func (s *Server) Friends(username string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.friends[username]...)
+}
+
+// Relationship returns the users having a relationship with a subreddit.
+func (s *Server) Relationship(subreddit, rel string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sr, ok := s.subreddits[subreddit]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), sr.Relationships[rel]...)
+}
+
+// isModerator reports whether user moderates subreddit. s.mu must be held.
+func (s *Server) isModerator(subreddit, user string) bool {
+	sr, ok := s.subreddits[subreddit]
+	return ok && contains(sr.Relationships["moderator"], user)
+}
+
+func contains(list []string, v string) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func remove(list []string, v string) []string {
+	out := list[:0]
+	for _, e := range list {
+		if e != v {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,303 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddittest
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The states of a modmail conversation.
+const (
+	conversationNew = iota
+	conversationInProgress
+	conversationArchived
+)
+
+// modmailActionTypes are the IDs reddit gives to the mod actions of the
+// conversation endpoints.
+var modmailActionTypes = map[string]int{
+	"highlight": 0,
+	"archive":   2,
+	"unarchive": 3,
+	"mute":      5,
+	"unmute":    6,
+}
+
+// conversation is a new modmail conversation of the fake server, between a
+// subreddit's moderators and a user.
+type conversation struct {
+	ID          string
+	Subreddit   string
+	Subject     string
+	User        string
+	State       int
+	Highlighted bool
+	Muted       bool
+	Unread      bool
+	Updated     time.Time
+	// objs are the messages and mod actions of the conversation, in order.
+	objs []*modmailObj
+}
+
+// modmailObj is a message or a mod action of a conversation.
+type modmailObj struct {
+	ID       string
+	Key      string // "messages" or "modActions"
+	Author   string
+	Body     string
+	Internal bool
+	Hidden   bool
+	Action   int
+	Date     time.Time
+}
+
+// AddConversation starts a modmail conversation from user to the
+// moderators of subreddit, and returns its ID.
+func (s *Server) AddConversation(subreddit, user, subject, body string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &conversation{
+		ID:        s.newID(),
+		Subreddit: subreddit,
+		Subject:   subject,
+		User:      user,
+		Unread:    true,
+		Updated:   s.now(),
+	}
+	c.objs = append(c.objs, &modmailObj{ID: s.newID(), Key: "messages", Author: user, Body: body, Date: c.Updated})
+	s.conversations = append(s.conversations, c)
+	return c.ID
+}
+
+// modmail serves the /api/mod/conversations endpoints; segs is the rest of
+// the path.
+func (s *Server) modmail(w http.ResponseWriter, r *http.Request, segs []string, user string) {
+	switch {
+	case len(segs) == 0 && r.Method == "GET":
+		s.conversationList(w, r, user)
+	case len(segs) == 1 && r.Method == "POST" && (segs[0] == "read" || segs[0] == "unread"):
+		for _, id := range strings.Split(r.Form.Get("conversationIds"), ",") {
+			if c := s.conversation(id, user); c != nil {
+				c.Unread = segs[0] == "unread"
+			}
+		}
+		writeJSON(w, http.StatusOK, struct{}{})
+	case len(segs) == 1 || len(segs) == 2:
+		c := s.conversation(segs[0], user)
+		if c == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		switch {
+		case len(segs) == 1 && r.Method == "GET":
+			if r.Form.Get("markRead") == "true" {
+				c.Unread = false
+			}
+		case len(segs) == 1 && r.Method == "POST":
+			obj := &modmailObj{
+				ID:       s.newID(),
+				Key:      "messages",
+				Author:   user,
+				Body:     r.Form.Get("body"),
+				Internal: r.Form.Get("isInternal") == "true",
+				Hidden:   r.Form.Get("isAuthorHidden") == "true",
+				Date:     s.now(),
+			}
+			c.objs = append(c.objs, obj)
+			c.Updated = obj.Date
+			if c.State == conversationNew {
+				c.State = conversationInProgress
+			}
+		case len(segs) == 2 && r.Method == "POST":
+			action, ok := modmailActionTypes[segs[1]]
+			if !ok {
+				writeError(w, http.StatusNotFound)
+				return
+			}
+			switch segs[1] {
+			case "highlight":
+				c.Highlighted = true
+			case "archive":
+				c.State = conversationArchived
+			case "unarchive":
+				c.State = conversationInProgress
+			case "mute", "unmute":
+				c.Muted = segs[1] == "mute"
+			}
+			c.objs = append(c.objs, &modmailObj{ID: s.newID(), Key: "modActions", Author: user, Action: action, Date: s.now()})
+		default:
+			writeError(w, http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeConversation(w, c)
+	default:
+		writeError(w, http.StatusNotFound)
+	}
+}
+
+// conversation returns the conversation with the given ID if user
+// moderates its subreddit.
+func (s *Server) conversation(id, user string) *conversation {
+	for _, c := range s.conversations {
+		if c.ID == id && s.isModerator(c.Subreddit, user) {
+			return c
+		}
+	}
+	return nil
+}
+
+// conversationList serves the conversations of the subreddits user
+// moderates in the state of the request, most recently updated first, with
+// their last message only like reddit.
+func (s *Server) conversationList(w http.ResponseWriter, r *http.Request, user string) {
+	state := r.Form.Get("state")
+	conversations := map[string]interface{}{}
+	messages := map[string]interface{}{}
+	ids := []string{}
+	for i := len(s.conversations) - 1; i >= 0; i-- {
+		c := s.conversations[i]
+		if !s.isModerator(c.Subreddit, user) {
+			continue
+		}
+		switch {
+		case state == "new" && c.State != conversationNew,
+			state == "inprogress" && c.State != conversationInProgress,
+			state == "archived" && c.State != conversationArchived,
+			state == "highlighted" && !c.Highlighted,
+			(state == "" || state == "all") && c.State == conversationArchived:
+			continue
+		}
+		ids = append(ids, c.ID)
+		data := s.conversationJSON(c)
+		for j := len(c.objs) - 1; j >= 0; j-- {
+			if obj := c.objs[j]; obj.Key == "messages" {
+				data["objIds"] = []map[string]string{{"id": obj.ID, "key": obj.Key}}
+				messages[obj.ID] = s.modmailObjJSON(c, obj)
+				break
+			}
+		}
+		conversations[c.ID] = data
+	}
+	if limit, err := strconv.Atoi(r.Form.Get("limit")); err == nil && limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"conversations":   conversations,
+		"conversationIds": ids,
+		"messages":        messages,
+		"viewerId":        "t2_" + user,
+	})
+}
+
+// writeConversation writes the response of the endpoints acting on a
+// single conversation: the conversation, and lookup tables of all of its
+// messages and mod actions.
+func (s *Server) writeConversation(w http.ResponseWriter, c *conversation) {
+	messages := map[string]interface{}{}
+	actions := map[string]interface{}{}
+	for _, obj := range c.objs {
+		if obj.Key == "messages" {
+			messages[obj.ID] = s.modmailObjJSON(c, obj)
+		} else {
+			actions[obj.ID] = s.modmailObjJSON(c, obj)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"conversation": s.conversationJSON(c),
+		"messages":     messages,
+		"modActions":   actions,
+		"user":         map[string]interface{}{"name": c.User, "id": "t2_" + c.User},
+	})
+}
+
+func (s *Server) conversationJSON(c *conversation) map[string]interface{} {
+	objIDs := make([]map[string]string, len(c.objs))
+	numMessages := 0
+	var lastUser, lastMod time.Time
+	authors := []interface{}{}
+	seen := map[string]bool{}
+	for i, obj := range c.objs {
+		objIDs[i] = map[string]string{"id": obj.ID, "key": obj.Key}
+		if obj.Key != "messages" {
+			continue
+		}
+		numMessages++
+		if obj.Author == c.User {
+			lastUser = obj.Date
+		} else {
+			lastMod = obj.Date
+		}
+		if !seen[obj.Author] {
+			seen[obj.Author] = true
+			authors = append(authors, s.participantJSON(c, obj.Author, false))
+		}
+	}
+	data := map[string]interface{}{
+		"id":             c.ID,
+		"subject":        c.Subject,
+		"state":          c.State,
+		"isAuto":         false,
+		"isHighlighted":  c.Highlighted,
+		"isInternal":     false,
+		"isRepliable":    true,
+		"numMessages":    numMessages,
+		"lastUpdated":    c.Updated,
+		"lastUserUpdate": nullableTime(lastUser),
+		"lastModUpdate":  nullableTime(lastMod),
+		"lastUnread":     nil,
+		"owner": map[string]string{
+			"id":          "t5_" + c.Subreddit,
+			"displayName": c.Subreddit,
+			"type":        "subreddit",
+		},
+		"authors":     authors,
+		"participant": s.participantJSON(c, c.User, false),
+		"objIds":      objIDs,
+	}
+	if c.Unread {
+		data["lastUnread"] = c.Updated
+	}
+	return data
+}
+
+func (s *Server) modmailObjJSON(c *conversation, obj *modmailObj) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":     obj.ID,
+		"author": s.participantJSON(c, obj.Author, obj.Hidden),
+		"date":   obj.Date,
+	}
+	if obj.Key == "messages" {
+		data["body"] = "<p>" + html.EscapeString(obj.Body) + "</p>"
+		data["bodyMarkdown"] = obj.Body
+		data["isInternal"] = obj.Internal
+	} else {
+		data["actionTypeId"] = obj.Action
+	}
+	return data
+}
+
+func (s *Server) participantJSON(c *conversation, name string, hidden bool) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            "t2_" + name,
+		"name":          name,
+		"isMod":         s.isModerator(c.Subreddit, name),
+		"isAdmin":       false,
+		"isOp":          name == c.User,
+		"isParticipant": name == c.User,
+		"isHidden":      hidden,
+		"isDeleted":     false,
+	}
+}
+
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
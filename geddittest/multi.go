@@ -0,0 +1,262 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddittest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// multi is a multireddit of the fake server.
+type multi struct {
+	Owner           string
+	Name            string
+	DisplayName     string
+	Desc            string
+	Visibility      string
+	WeightingScheme string
+	KeyColor        string
+	CopiedFrom      string
+	Subreddits      []string
+	Created         time.Time
+}
+
+func (m *multi) path() string {
+	return "/user/" + m.Owner + "/m/" + m.Name
+}
+
+// multiModel is the JSON description of a multireddit clients send to
+// create or update it.
+type multiModel struct {
+	DisplayName     string `json:"display_name"`
+	Desc            string `json:"description_md"`
+	Visibility      string `json:"visibility"`
+	WeightingScheme string `json:"weighting_scheme"`
+	KeyColor        string `json:"key_color"`
+	Subreddits      []struct {
+		Name string `json:"name"`
+	} `json:"subreddits"`
+}
+
+// multiName turns a display name into the name of a multireddit, as reddit
+// does when copying or renaming.
+func multiName(displayName string) string {
+	name := slug(displayName)
+	if name == "" {
+		name = "multi"
+	}
+	return name
+}
+
+// multiAPI serves the /api/multi endpoints; segs is the rest of the path.
+func (s *Server) multiAPI(w http.ResponseWriter, r *http.Request, segs []string, user string) {
+	if user == "" {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	n := len(segs)
+	switch {
+	case n == 1 && segs[0] == "mine" && r.Method == "GET":
+		s.writeMultis(w, user, user)
+	case n == 2 && segs[0] == "user" && r.Method == "GET":
+		s.writeMultis(w, segs[1], user)
+	case n == 1 && (segs[0] == "copy" || segs[0] == "rename") && r.Method == "POST":
+		s.copyMulti(w, r, segs[0] == "rename", user)
+	case n == 4 && segs[0] == "user" && segs[2] == "m":
+		s.multi(w, r, segs[1], segs[3], user)
+	case n == 6 && segs[0] == "user" && segs[2] == "m" && segs[4] == "r":
+		s.multiSubreddit(w, r, segs[1], segs[3], segs[5], user)
+	default:
+		writeError(w, http.StatusNotFound)
+	}
+}
+
+// visibleMulti returns the multireddit at path if user may see it.
+func (s *Server) visibleMulti(path, user string) *multi {
+	m := s.multis[path]
+	if m == nil || (m.Owner != user && m.Visibility != "public") {
+		return nil
+	}
+	return m
+}
+
+// writeMultis writes the multireddits of owner that user may see.
+func (s *Server) writeMultis(w http.ResponseWriter, owner, user string) {
+	var paths []string
+	for path, m := range s.multis {
+		if m.Owner == owner && s.visibleMulti(path, user) != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	things := []thing{}
+	for _, path := range paths {
+		things = append(things, thing{"LabeledMulti", s.multiJSON(s.multis[path], user)})
+	}
+	writeJSON(w, http.StatusOK, things)
+}
+
+// multi serves a single multireddit: GET returns it, PUT creates or
+// updates it from its model and DELETE deletes it.
+func (s *Server) multi(w http.ResponseWriter, r *http.Request, owner, name, user string) {
+	path := "/user/" + owner + "/m/" + name
+	switch r.Method {
+	case "GET":
+		m := s.visibleMulti(path, user)
+		if m == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, thing{"LabeledMulti", s.multiJSON(m, user)})
+	case "PUT":
+		if owner != user {
+			writeError(w, http.StatusForbidden)
+			return
+		}
+		var model multiModel
+		if err := json.Unmarshal([]byte(r.Form.Get("model")), &model); err != nil {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+		status := http.StatusOK
+		m := s.multis[path]
+		if m == nil {
+			m = &multi{Owner: owner, Name: name, Visibility: "private", WeightingScheme: "classic", Created: s.now()}
+			s.multis[path] = m
+			status = http.StatusCreated
+		}
+		m.DisplayName = model.DisplayName
+		if m.DisplayName == "" {
+			m.DisplayName = name
+		}
+		m.Desc = model.Desc
+		if model.Visibility != "" {
+			m.Visibility = model.Visibility
+		}
+		if model.WeightingScheme != "" {
+			m.WeightingScheme = model.WeightingScheme
+		}
+		m.KeyColor = model.KeyColor
+		m.Subreddits = nil
+		for _, sr := range model.Subreddits {
+			m.Subreddits = append(m.Subreddits, sr.Name)
+		}
+		writeJSON(w, status, thing{"LabeledMulti", s.multiJSON(m, user)})
+	case "DELETE":
+		if owner != user || s.multis[path] == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		delete(s.multis, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+// copyMulti copies the multireddit of the from form value into one of user
+// named after display_name, deleting the original when renaming.
+func (s *Server) copyMulti(w http.ResponseWriter, r *http.Request, renaming bool, user string) {
+	from := s.visibleMulti(r.Form.Get("from"), user)
+	if from == nil || (renaming && from.Owner != user) {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	m := *from
+	m.Owner = user
+	m.DisplayName = r.Form.Get("display_name")
+	m.Name = multiName(m.DisplayName)
+	m.Subreddits = append([]string(nil), from.Subreddits...)
+	if _, exists := s.multis[m.path()]; exists {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"fields": []string{"multipath"}, "explanation": "that multireddit already exists", "reason": "MULTI_EXISTS",
+		})
+		return
+	}
+	if renaming {
+		delete(s.multis, from.path())
+	} else {
+		m.CopiedFrom = from.path()
+		m.Visibility = "private"
+		m.Created = s.now()
+	}
+	s.multis[m.path()] = &m
+	writeJSON(w, http.StatusOK, thing{"LabeledMulti", s.multiJSON(&m, user)})
+}
+
+// multiSubreddit adds a subreddit to a multireddit of user with PUT, and
+// removes it with DELETE.
+func (s *Server) multiSubreddit(w http.ResponseWriter, r *http.Request, owner, name, sub, user string) {
+	m := s.multis["/user/"+owner+"/m/"+name]
+	if m == nil || owner != user {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case "PUT":
+		if !contains(m.Subreddits, sub) {
+			m.Subreddits = append(m.Subreddits, sub)
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"name": sub})
+	case "DELETE":
+		m.Subreddits = remove(m.Subreddits, sub)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+// multiListing serves the links of the subreddits of a multireddit.
+func (s *Server) multiListing(w http.ResponseWriter, r *http.Request, owner, name, order, user string) {
+	m := s.visibleMulti("/user/"+owner+"/m/"+name, user)
+	if m == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	var links []*Link
+	for _, id := range s.linkOrder {
+		l := s.links[id]
+		if contains(m.Subreddits, l.Subreddit) && (!l.Removed || s.isModerator(l.Subreddit, user)) {
+			links = append(links, l)
+		}
+	}
+	sortLinks(links, order)
+	things := make([]thing, len(links))
+	for i, l := range links {
+		things[i] = thing{"t3", s.linkJSON(l, user)}
+	}
+	writeListing(w, r, things)
+}
+
+func (s *Server) multiJSON(m *multi, user string) map[string]interface{} {
+	subreddits := make([]map[string]string, len(m.Subreddits))
+	for i, name := range m.Subreddits {
+		subreddits[i] = map[string]string{"name": name}
+	}
+	var copiedFrom interface{}
+	if m.CopiedFrom != "" {
+		copiedFrom = m.CopiedFrom
+	}
+	return map[string]interface{}{
+		"name":             m.Name,
+		"display_name":     m.DisplayName,
+		"path":             m.path(),
+		"owner":            m.Owner,
+		"description_md":   m.Desc,
+		"description_html": "",
+		"visibility":       m.Visibility,
+		"weighting_scheme": m.WeightingScheme,
+		"key_color":        m.KeyColor,
+		"icon_url":         "",
+		"copied_from":      copiedFrom,
+		"created_utc":      unix(m.Created),
+		"num_subscribers":  0,
+		"can_edit":         m.Owner == user,
+		"over_18":          false,
+		"subreddits":       subreddits,
+	}
+}
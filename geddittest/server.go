@@ -0,0 +1,269 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package geddittest provides an in-process stand-in for reddit, so code
+// using geddit can be tested without network access or real accounts.
+//
+// The server keeps an in-memory model of accounts, subreddits, links,
+// comments and messages, seeded with Fixtures, and serves the token,
+// listing, comments, morechildren, vote, submit, comment, message,
+// moderation, moderation log, wiki, user flair, modmail, multireddit and
+// account endpoints from it. Faults such as 429s, 5xx responses and
+// reddit json.errors can be injected, every response carries reddit's
+// rate limit headers, and GET responses carry an ETag honored by
+// conditional requests.
+//
+// Sessions are pointed at the server with the client it returns:
+//
+//	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+//	defer srv.Close()
+//	session, err := geddit.NewOAuthSession(geddittest.Username,
+//		geddittest.Password, "tests", geddittest.ClientID,
+//		geddittest.ClientSecret, geddit.WithHTTPClient(srv.Client()))
+package geddittest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake reddit. All of its methods are safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the server, for clients that can be pointed
+	// at it directly.
+	URL string
+
+	srv *httptest.Server
+
+	mu            sync.Mutex
+	apps          map[string]string
+	accounts      map[string]*Account
+	subreddits    map[string]*Subreddit
+	links         map[string]*Link
+	linkOrder     []string
+	comments      map[string]*Comment
+	commentOrder  []string
+	messages      []*Message
+	votes         map[string]int
+	friends       map[string][]string
+	blocked       map[string][]string
+	preferences   map[string]map[string]interface{}
+	tokens        map[string]*token
+	refresh       map[string]string
+	cookies       map[string]string
+	lastID        int64
+	faults        []*Fault
+	requests      int
+	modActions    []*modAction
+	wiki          map[string][]*wikiRevision
+	flairs        map[string]map[string]*userFlair
+	conversations []*conversation
+	multis        map[string]*multi
+	scope         string
+
+	tokenTTL    time.Duration
+	rateLimit   int
+	rateWindow  time.Duration
+	rateUsed    int
+	windowStart time.Time
+}
+
+// token is an access token issued by the server.
+type token struct {
+	user    string
+	expires time.Time
+}
+
+// NewServer starts a fake reddit serving f. f may be nil for an empty
+// world.
+func NewServer(f *Fixtures) *Server {
+	s := &Server{
+		apps:        make(map[string]string),
+		accounts:    make(map[string]*Account),
+		subreddits:  make(map[string]*Subreddit),
+		links:       make(map[string]*Link),
+		comments:    make(map[string]*Comment),
+		votes:       make(map[string]int),
+		friends:     make(map[string][]string),
+		blocked:     make(map[string][]string),
+		tokens:      make(map[string]*token),
+		refresh:     make(map[string]string),
+		cookies:     make(map[string]string),
+		wiki:        make(map[string][]*wikiRevision),
+		flairs:      make(map[string]map[string]*userFlair),
+		multis:      make(map[string]*multi),
+		preferences: make(map[string]map[string]interface{}),
+		lastID:      36 * 36 * 36,
+		tokenTTL:    time.Hour,
+		rateLimit:   600,
+		rateWindow:  10 * time.Minute,
+		scope:       "*",
+	}
+	if f != nil {
+		s.Load(f)
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns an HTTP client sending every request to the server,
+// whatever host it was meant for. It can be given to a session with
+// SetHTTPClient or WithHTTPClient.
+func (s *Server) Client() *http.Client {
+	base := s.srv.Client().Transport
+	return &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.URL.Scheme = "http"
+			req.URL.Host = s.srv.Listener.Addr().String()
+			req.Host = ""
+			return base.RoundTrip(req)
+		}),
+		Timeout: 30 * time.Second,
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Requests returns the number of requests the server received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// SetTokenTTL sets how long the access tokens issued from now on are valid,
+// one hour by default.
+func (s *Server) SetTokenTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenTTL = ttl
+}
+
+// SetScopes sets the OAuth scopes granted to the access tokens issued from
+// now on that do not request any, every scope ("*") by default.
+func (s *Server) SetScopes(scopes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scope = strings.Join(scopes, " ")
+}
+
+// ExpireTokens invalidates every access token issued so far, as if they had
+// all expired. Refresh tokens keep working.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		t.expires = time.Time{}
+	}
+}
+
+// SetRateLimit makes the server allow limit requests per window, after
+// which it answers 429 until the window ends. The default is reddit's 600
+// requests per 10 minutes.
+func (s *Server) SetRateLimit(limit int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = limit
+	s.rateWindow = window
+	s.rateUsed = 0
+	s.windowStart = time.Time{}
+}
+
+// Fault describes a failure to inject into matching requests.
+type Fault struct {
+	// Method and Path select the requests to fail; empty values match any.
+	// Path is a path.Match pattern matched against the URL path without
+	// its .json suffix, e.g. "/r/*/new".
+	Method string
+	Path   string
+
+	// Status is the HTTP status to answer with, e.g. 429 or 503. When
+	// Errors is set instead, the request is answered 200 with reddit's
+	// {"json": {"errors": ...}} envelope.
+	Status int
+	Errors [][]string
+
+	// RetryAfter is sent in the Retry-After header of 429 responses.
+	RetryAfter time.Duration
+
+	// Times is how many requests fail before the fault goes away; zero
+	// means until ClearFaults is called.
+	Times int
+}
+
+// Inject adds a fault. Faults are tried in the order they were added.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// fault returns the fault to apply to a request, if any. s.mu must be held.
+func (s *Server) fault(method, urlpath string) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != method {
+			continue
+		}
+		if f.Path != "" {
+			if ok, _ := path.Match(f.Path, urlpath); !ok {
+				continue
+			}
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// rateLimited updates the rate limit counters, sets the rate limit headers
+// and reports whether the request is over the limit. s.mu must be held.
+func (s *Server) rateLimited(h http.Header) bool {
+	now := s.now()
+	if now.Sub(s.windowStart) >= s.rateWindow {
+		s.windowStart = now
+		s.rateUsed = 0
+	}
+	s.rateUsed++
+	remaining := s.rateLimit - s.rateUsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	reset := s.windowStart.Add(s.rateWindow).Sub(now)
+	h.Set("X-Ratelimit-Used", strconv.Itoa(s.rateUsed))
+	h.Set("X-Ratelimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-Ratelimit-Reset", strconv.Itoa(int(reset.Seconds())))
+	return s.rateUsed > s.rateLimit
+}
+
+func (s *Server) now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,179 @@
+package geddittest_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jzelinskie/geddit"
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func newServer(t *testing.T) *geddittest.Server {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func oauthSession(t *testing.T, srv *geddittest.Server, username string) *geddit.OAuthSession {
+	s, err := geddit.NewOAuthSession(username, geddittest.Password, "geddittest",
+		geddittest.ClientID, geddittest.ClientSecret, geddit.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestOAuthIdentity(t *testing.T) {
+	srv := newServer(t)
+	me, err := oauthSession(t, srv, geddittest.Username).Me()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Name != geddittest.Username || me.LinkKarma != 10 {
+		t.Errorf("got %v, want %s with 10 link karma", me, geddittest.Username)
+	}
+}
+
+func TestListingAndComments(t *testing.T) {
+	srv := newServer(t)
+	s := geddit.NewSession("geddittest")
+	s.SetHTTPClient(srv.Client())
+
+	subs, err := s.SubredditSubmissions("golang", geddit.NewSubmissions, geddit.ListingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || subs[0].ID != "l2" {
+		t.Fatalf("got %v, want l2 then l1", subs)
+	}
+
+	comments, err := s.Comments(subs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || len(comments[0].Replies) != 1 {
+		t.Fatalf("got %v, want one comment with one reply, the other collapsed", comments)
+	}
+
+	body, err := s.Get(&url.Values{
+		"link_id":  {"t3_l1"},
+		"children": {"c3"},
+		"api_type": {"json"},
+	}, "/api/morechildren")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"body":"Also, vendoring."`; !strings.Contains(body.String(), want) {
+		t.Errorf("morechildren returned %s, want it to contain %s", body, want)
+	}
+}
+
+func TestSubmitAndVote(t *testing.T) {
+	srv := newServer(t)
+	s := oauthSession(t, srv, geddittest.Username)
+
+	body, err := s.Post(&url.Values{
+		"sr":       {"golang"},
+		"kind":     {"self"},
+		"title":    {"Hello"},
+		"text":     {"World"},
+		"api_type": {"json"},
+	}, "/api/submit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body.String(), `"errors":[]`) {
+		t.Fatalf("submit returned %s", body)
+	}
+	if n := len(srv.Links("golang")); n != 3 {
+		t.Fatalf("got %d links, want 3", n)
+	}
+
+	if _, err = s.Post(&url.Values{"id": {"t3_l2"}, "dir": {"-1"}}, "/api/vote"); err != nil {
+		t.Fatal(err)
+	}
+	if l, _ := srv.Link("l2"); l.Score != 4 {
+		t.Errorf("got score %d, want 4", l.Score)
+	}
+	if v := srv.Vote(geddittest.Username, "t3_l2"); v != -1 {
+		t.Errorf("got vote %d, want -1", v)
+	}
+}
+
+func TestMessagesAndModeration(t *testing.T) {
+	srv := newServer(t)
+	user := oauthSession(t, srv, geddittest.Username)
+	mod := oauthSession(t, srv, geddittest.Moderator)
+
+	r, err := user.User(geddittest.Moderator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.SendMessage("hi", "please unban me"); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := srv.Messages(geddittest.Moderator); len(msgs) != 1 || msgs[0].Subject != "hi" {
+		t.Fatalf("got messages %v", msgs)
+	}
+
+	err = mod.Friend("golang", geddittest.Username, geddit.BannedRelationship, geddit.FriendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := mod.Banned("golang", geddit.ListingOptions{})
+	if !it.Next() || it.Relationship().Name != geddittest.Username {
+		t.Fatalf("%s is not listed as banned: %v", geddittest.Username, it.Err())
+	}
+
+	// Non-moderators cannot ban.
+	err = user.Friend("golang", geddittest.Moderator, geddit.BannedRelationship, geddit.FriendOptions{})
+	if serr, ok := err.(*geddit.StatusError); !ok || serr.StatusCode != http.StatusForbidden {
+		t.Errorf("got %v, want a 403", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	srv := newServer(t)
+	s := oauthSession(t, srv, geddittest.Username)
+
+	srv.Inject(geddittest.Fault{Path: "/api/v1/me", Status: http.StatusTooManyRequests, Times: 1})
+	_, err := s.Me()
+	if serr, ok := err.(*geddit.StatusError); !ok || serr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got %v, want a 429", err)
+	}
+	if _, err = s.Me(); err != nil {
+		t.Fatalf("fault was not cleared after one request: %v", err)
+	}
+
+	srv.Inject(geddittest.Fault{Path: "/api/compose", Errors: [][]string{{"RATELIMIT", "you are doing that too much", "ratelimit"}}})
+	r, err := s.User(geddittest.Moderator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.SendMessage("hi", "there"); err == nil || err.Error() != "you are doing that too much" {
+		t.Errorf("got %v, want the injected json error", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	srv := newServer(t)
+	c := srv.Client()
+
+	srv.SetRateLimit(2, time.Minute)
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		resp, err := c.Get("https://www.reddit.com/r/golang/about.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("request %d: got status %d, want %d", i, resp.StatusCode, want)
+		}
+		if resp.Header.Get("X-Ratelimit-Remaining") == "" {
+			t.Errorf("request %d has no rate limit headers", i)
+		}
+	}
+}
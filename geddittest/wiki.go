@@ -0,0 +1,89 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddittest
+
+import (
+	"html"
+	"net/http"
+	"time"
+)
+
+// wikiRevision is a revision of a wiki page of the fake server.
+type wikiRevision struct {
+	ID      string
+	Content string
+	Reason  string
+	Author  string
+	Created time.Time
+}
+
+// wikiPage serves the current revision of a wiki page, or the one given in
+// the v parameter.
+func (s *Server) wikiPage(w http.ResponseWriter, r *http.Request, sub, page string) {
+	revisions := s.wiki[sub+"/"+page]
+	if _, ok := s.subreddits[sub]; !ok || len(revisions) == 0 {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	rev := revisions[len(revisions)-1]
+	if v := r.Form.Get("v"); v != "" {
+		rev = nil
+		for _, candidate := range revisions {
+			if candidate.ID == v {
+				rev = candidate
+			}
+		}
+		if rev == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, thing{"wikipage", map[string]interface{}{
+		"content_md":    rev.Content,
+		"content_html":  "<p>" + html.EscapeString(rev.Content) + "</p>",
+		"may_revise":    true,
+		"reason":        nullable(rev.Reason),
+		"revision_id":   rev.ID,
+		"revision_date": unix(rev.Created),
+		"revision_by":   thing{"t2", s.accountJSON(s.accounts[rev.Author])},
+	}})
+}
+
+// wikiEdit adds a revision to a wiki page, creating it if needed. Like
+// reddit, it answers 409 Conflict with the current revision when the edit
+// is based on an older one than the current.
+func (s *Server) wikiEdit(w http.ResponseWriter, r *http.Request, sub, user string) {
+	if _, ok := s.subreddits[sub]; !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	if user == "" {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	key := sub + "/" + r.Form.Get("page")
+	revisions := s.wiki[key]
+	content := r.Form.Get("content")
+	if previous := r.Form.Get("previous"); previous != "" && len(revisions) > 0 {
+		if current := revisions[len(revisions)-1]; current.ID != previous {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{
+				"message":     http.StatusText(http.StatusConflict),
+				"reason":      "EDIT_CONFLICT",
+				"newrevision": current.ID,
+				"newcontent":  current.Content,
+				"diffcontent": "<del>" + html.EscapeString(current.Content) + "</del><ins>" + html.EscapeString(content) + "</ins>",
+			})
+			return
+		}
+	}
+	s.wiki[key] = append(revisions, &wikiRevision{
+		ID:      "wr_" + s.newID(),
+		Content: content,
+		Reason:  r.Form.Get("reason"),
+		Author:  user,
+		Created: s.now(),
+	})
+	writeJSON(w, http.StatusOK, struct{}{})
+}
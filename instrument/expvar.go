@@ -0,0 +1,136 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package instrument
+
+import (
+	"encoding/json"
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency
+// histogram buckets used by Expvar.
+var DefaultBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// Expvar is a Recorder publishing its metrics with the expvar package, as a
+// map with the following keys:
+//
+//	requests              counts by "METHOD endpoint class"
+//	latency               histograms by "METHOD endpoint"
+//	retries               counts by "METHOD endpoint"
+//	ratelimit_remaining   the last X-Ratelimit-Remaining seen
+//	token_refreshes       counts by "ok" and "error"
+type Expvar struct {
+	requests       *expvar.Map
+	latency        *expvar.Map
+	retries        *expvar.Map
+	remaining      *expvar.Float
+	tokenRefreshes *expvar.Map
+
+	mu      sync.Mutex
+	buckets []float64
+}
+
+// NewExpvar creates an Expvar recorder and publishes it under name. Like
+// expvar.Publish, it panics if name is already in use.
+func NewExpvar(name string) *Expvar {
+	e := &Expvar{
+		requests:       new(expvar.Map),
+		latency:        new(expvar.Map),
+		retries:        new(expvar.Map),
+		remaining:      new(expvar.Float),
+		tokenRefreshes: new(expvar.Map),
+		buckets:        DefaultBuckets,
+	}
+	m := expvar.NewMap(name)
+	m.Set("requests", e.requests)
+	m.Set("latency", e.latency)
+	m.Set("retries", e.retries)
+	m.Set("ratelimit_remaining", e.remaining)
+	m.Set("token_refreshes", e.tokenRefreshes)
+	return e
+}
+
+// ObserveRequest implements Recorder.
+func (e *Expvar) ObserveRequest(method, endpoint, class string, d time.Duration) {
+	e.requests.Add(method+" "+endpoint+" "+class, 1)
+
+	key := method + " " + endpoint
+	e.mu.Lock()
+	h, ok := e.latency.Get(key).(*histogram)
+	if !ok {
+		h = newHistogram(e.buckets)
+		e.latency.Set(key, h)
+	}
+	e.mu.Unlock()
+	h.observe(d.Seconds())
+}
+
+// ObserveRetry implements Recorder.
+func (e *Expvar) ObserveRetry(method, endpoint string) {
+	e.retries.Add(method+" "+endpoint, 1)
+}
+
+// SetRateLimitRemaining implements Recorder.
+func (e *Expvar) SetRateLimitRemaining(remaining float64) {
+	e.remaining.Set(remaining)
+}
+
+// ObserveTokenRefresh implements Recorder.
+func (e *Expvar) ObserveTokenRefresh(ok bool) {
+	if ok {
+		e.tokenRefreshes.Add("ok", 1)
+	} else {
+		e.tokenRefreshes.Add("error", 1)
+	}
+}
+
+// histogram is a cumulative latency histogram exported as JSON.
+type histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	h.sum += v
+	for i := sort.SearchFloat64s(h.bounds, v); i < len(h.bounds); i++ {
+		h.counts[i]++
+	}
+}
+
+// String implements expvar.Var.
+func (h *histogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buckets := make(map[string]uint64, len(h.bounds))
+	for i, b := range h.bounds {
+		buckets[formatBound(b)] = h.counts[i]
+	}
+	b, _ := json.Marshal(struct {
+		Buckets map[string]uint64 `json:"buckets"`
+		Count   uint64            `json:"count"`
+		Sum     float64           `json:"sum"`
+	}{buckets, h.count, h.sum})
+	return string(b)
+}
+
+func formatBound(b float64) string {
+	return time.Duration(b * float64(time.Second)).String()
+}
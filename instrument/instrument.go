@@ -0,0 +1,165 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package instrument records metrics about the requests a geddit session
+// performs: request counts and latencies by endpoint and status class,
+// retries, the remaining rate limit and token refreshes.
+//
+// Metrics go to a Recorder, which can be backed by any metrics system. An
+// expvar implementation is provided; a Prometheus one only needs a counter
+// vector and a histogram vector labeled by method, endpoint and class:
+//
+//	session.Use(instrument.Middleware(instrument.NewExpvar("reddit")))
+package instrument
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jzelinskie/geddit"
+)
+
+// Recorder receives the metrics of a session's requests. Endpoints are
+// normalized templates such as /r/{sub}/new, so they can be used as labels
+// without exploding cardinality. Implementations must be safe for
+// concurrent use.
+type Recorder interface {
+	// ObserveRequest is called once per completed request. class is one of
+	// "1xx" to "5xx", or "error" when no response was received.
+	ObserveRequest(method, endpoint, class string, d time.Duration)
+	// ObserveRetry is called for requests marked with geddit.MarkRetry,
+	// such as the ones a session re-sends with a renewed access token.
+	ObserveRetry(method, endpoint string)
+	// SetRateLimitRemaining is called with reddit's X-Ratelimit-Remaining
+	// header whenever a response carries it.
+	SetRateLimitRemaining(remaining float64)
+	// ObserveTokenRefresh is called after every access token request.
+	ObserveTokenRefresh(ok bool)
+}
+
+// tokenEndpoint is the path of reddit's access token endpoint.
+const tokenEndpoint = "/api/v1/access_token"
+
+// Middleware returns a geddit.Middleware reporting to r. Put it last in the
+// chain for latencies to exclude the time spent in other middlewares.
+func Middleware(r Recorder) geddit.Middleware {
+	return func(next geddit.Doer) geddit.Doer {
+		return geddit.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			endpoint := Endpoint(req.URL.Path)
+			if geddit.IsRetry(req) {
+				r.ObserveRetry(req.Method, endpoint)
+			}
+
+			start := time.Now()
+			resp, err := next.Do(req)
+			d := time.Since(start)
+
+			status := 0
+			if err == nil {
+				status = resp.StatusCode
+				if v, perr := strconv.ParseFloat(resp.Header.Get("X-Ratelimit-Remaining"), 64); perr == nil {
+					r.SetRateLimitRemaining(v)
+				}
+			}
+			r.ObserveRequest(req.Method, endpoint, StatusClass(status), d)
+			if endpoint == tokenEndpoint {
+				r.ObserveTokenRefresh(status == http.StatusOK)
+			}
+			return resp, err
+		})
+	}
+}
+
+// StatusClass returns the class of an HTTP status code, e.g. "4xx". A zero
+// status, meaning no response was received, is reported as "error".
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "error"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// placeholders maps path segments to the placeholder of the segment
+// following them.
+var placeholders = map[string]string{
+	"r":             "{sub}",
+	"user":          "{user}",
+	"u":             "{user}",
+	"m":             "{multi}",
+	"by_id":         "{ids}",
+	"live":          "{thread}",
+	"friends":       "{user}",
+	"conversations": "{conversation}",
+	"messages":      "{message}",
+	"duplicates":    "{id}",
+}
+
+// literals are segments kept as is even where a placeholder is expected,
+// because they are fixed endpoints rather than names.
+var literals = map[string]bool{
+	"create":        true,
+	"happening_now": true,
+	"read":          true,
+	"unread":        true,
+	"mine":          true,
+	"copy":          true,
+	"rename":        true,
+}
+
+// wikiPages are the wiki endpoints that are not page names.
+var wikiPages = map[string]bool{
+	"pages":       true,
+	"revisions":   true,
+	"settings":    true,
+	"discussions": true,
+}
+
+// Endpoint normalizes a reddit URL path into a template by replacing the
+// names and IDs it contains with placeholders, e.g.
+// /r/golang/comments/abc123/some_title.json becomes
+// /r/{sub}/comments/{id}/{slug}. Query strings are not expected.
+func Endpoint(path string) string {
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".json")
+	// Comments pages are fetched as /comments/{id}/.json.
+	path = strings.TrimSuffix(path, "/")
+	segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	out := make([]string, 0, len(segs))
+	for i := 0; i < len(segs); i++ {
+		seg := segs[i]
+		out = append(out, seg)
+		if i+1 >= len(segs) {
+			break
+		}
+		next := segs[i+1]
+
+		switch {
+		case seg == "comments":
+			// comments/{id}/{slug}/{comment}
+			for _, p := range []string{"{id}", "{slug}", "{comment}"} {
+				if i+1 >= len(segs) {
+					break
+				}
+				i++
+				out = append(out, p)
+			}
+		case seg == "wiki" && len(out) > 1 && out[len(out)-2] == "{sub}":
+			// Page names may contain slashes, so the rest is the page.
+			if wikiPages[next] {
+				out = append(out, next)
+				i++
+			}
+			if i+1 < len(segs) {
+				out = append(out, "{page}")
+			}
+			return "/" + strings.Join(out, "/")
+		case placeholders[seg] != "" && !literals[next]:
+			out = append(out, placeholders[seg])
+			i++
+		}
+	}
+	return "/" + strings.Join(out, "/")
+}
@@ -0,0 +1,104 @@
+package instrument
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jzelinskie/geddit"
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/", "/"},
+		{"/r/golang", "/r/{sub}"},
+		{"/r/golang/", "/r/{sub}"},
+		{"/r/golang/new.json", "/r/{sub}/new"},
+		{"/r/golang/about.json", "/r/{sub}/about"},
+		{"/r/golang/comments/abc123/some_title", "/r/{sub}/comments/{id}/{slug}"},
+		{"/r/golang/comments/abc123/some_title.json", "/r/{sub}/comments/{id}/{slug}"},
+		{"/r/golang/comments/abc123/some_title/def456/", "/r/{sub}/comments/{id}/{slug}/{comment}"},
+		{"/comments/abc123", "/comments/{id}"},
+		{"/comments/abc123/.json", "/comments/{id}"},
+		{"/r/golang/wiki", "/r/{sub}/wiki"},
+		{"/r/golang/wiki/index", "/r/{sub}/wiki/{page}"},
+		{"/r/golang/wiki/config/sidebar", "/r/{sub}/wiki/{page}"},
+		{"/r/golang/wiki/pages", "/r/{sub}/wiki/pages"},
+		{"/r/golang/wiki/revisions/config/sidebar", "/r/{sub}/wiki/revisions/{page}"},
+		{"/r/golang/wiki/settings/config/sidebar", "/r/{sub}/wiki/settings/{page}"},
+		{"/api/v1/me", "/api/v1/me"},
+		{"/api/v1/me/friends/someone", "/api/v1/me/friends/{user}"},
+		{"/api/v1/access_token", "/api/v1/access_token"},
+		{"/user/gopher/about.json", "/user/{user}/about"},
+		{"/api/multi/mine", "/api/multi/mine"},
+		{"/api/multi/user/gopher", "/api/multi/user/{user}"},
+		{"/api/multi/user/gopher/m/feed/copy", "/api/multi/user/{user}/m/{multi}/copy"},
+		{"/api/live/create", "/api/live/create"},
+		{"/api/live/happening_now", "/api/live/happening_now"},
+		{"/live/abc/about", "/live/{thread}/about"},
+		{"/api/live/abc/update", "/api/live/{thread}/update"},
+		{"/api/mod/conversations/read", "/api/mod/conversations/read"},
+		{"/api/mod/conversations/unread", "/api/mod/conversations/unread"},
+		{"/api/mod/conversations/abc/archive", "/api/mod/conversations/{conversation}/archive"},
+		{"/by_id/t3_a,t3_b", "/by_id/{ids}"},
+	}
+	for _, tt := range tests {
+		if got := Endpoint(tt.path); got != tt.want {
+			t.Errorf("Endpoint(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// countingRecorder counts retries and token refreshes.
+type countingRecorder struct {
+	mu       sync.Mutex
+	retries  map[string]int
+	requests int
+	tokens   int
+}
+
+func (r *countingRecorder) ObserveRequest(method, endpoint, class string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+}
+
+func (r *countingRecorder) ObserveRetry(method, endpoint string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries[method+" "+endpoint]++
+}
+
+func (r *countingRecorder) SetRateLimitRemaining(remaining float64) {}
+
+func (r *countingRecorder) ObserveTokenRefresh(ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens++
+}
+
+func TestMiddlewareObservesTokenRetry(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	rec := &countingRecorder{retries: make(map[string]int)}
+	s, err := geddit.NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret,
+		geddit.WithHTTPClient(srv.Client()), geddit.WithMiddleware(Middleware(rec)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ExpireTokens()
+	if _, err = s.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if n := rec.retries["GET /api/v1/me"]; n != 1 {
+		t.Errorf("got %d retries of GET /api/v1/me, want 1 (all: %v)", n, rec.retries)
+	}
+	if rec.tokens != 2 {
+		t.Errorf("got %d token refreshes, want 2", rec.tokens)
+	}
+}
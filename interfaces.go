@@ -0,0 +1,66 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+// These names make the option types of the session methods below
+// available outside the package, so that fakes can implement the
+// interfaces.
+type (
+	PopularitySort   = popularitySort
+	VoteDirection    = vote
+	NewSubmission    = newSubmission
+	RelationshipType = relationshipType
+)
+
+// Reader is implemented by sessions able to read listings, comments and
+// information about users and subreddits: Session, LoginSession and
+// OAuthSession. Code depending on it rather than on a session type can be
+// tested with a fake, such as the ones of package gedditfake.
+type Reader interface {
+	DefaultFrontpage(sort PopularitySort, params ListingOptions) ([]*Submission, error)
+	SubredditSubmissions(subreddit string, sort PopularitySort, params ListingOptions) ([]*Submission, error)
+	AboutSubreddit(subreddit string) (*Subreddit, error)
+	AboutRedditor(username string) (*Redditor, error)
+	Comments(h *Submission) ([]*Comment, error)
+}
+
+// Actor is implemented by sessions able to submit, vote, reply and delete
+// on behalf of a user: LoginSession and OAuthSession.
+type Actor interface {
+	Submit(ns *NewSubmission) error
+	Vote(v Voter, dir VoteDirection) error
+	Reply(r Replier, comment string) error
+	Delete(d Deleter) error
+}
+
+// Messenger is implemented by sessions able to send and read private
+// messages: LoginSession and OAuthSession.
+type Messenger interface {
+	SendMessage(to, subject, text string) error
+	Inbox(params ListingOptions) ([]*Message, error)
+	MarkMessagesRead(ids ...string) error
+}
+
+// Moderator is implemented by sessions able to moderate subreddits:
+// LoginSession and OAuthSession.
+type Moderator interface {
+	Approve(fullID string) error
+	Remove(fullID string, spam bool) error
+	Friend(subreddit, username string, rel RelationshipType, opts FriendOptions) error
+	Unfriend(subreddit, username string, rel RelationshipType) error
+}
+
+var (
+	_ Reader    = (*Session)(nil)
+	_ Reader    = (*LoginSession)(nil)
+	_ Reader    = (*OAuthSession)(nil)
+	_ Reader    = (*Pool)(nil)
+	_ Actor     = (*LoginSession)(nil)
+	_ Actor     = (*OAuthSession)(nil)
+	_ Messenger = (*LoginSession)(nil)
+	_ Messenger = (*OAuthSession)(nil)
+	_ Moderator = (*LoginSession)(nil)
+	_ Moderator = (*OAuthSession)(nil)
+)
@@ -0,0 +1,149 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+)
+
+// GetJSON performs a GET request against the OAuth API with s and decodes
+// the response into a T, see PostJSON.
+func GetJSON[T any](s *OAuthSession, params *url.Values, urlformat string, urlvars ...interface{}) (T, error) {
+	return doJSON[T](s, GET, params, urlformat, urlvars...)
+}
+
+// PostJSON performs a POST request against the OAuth API with s and
+// decodes the response into a T.
+//
+// A body of type url.Values or *url.Values is sent as a form, with
+// api_type=json added so reddit reports errors in its JSON envelope; the
+// caller's values are left untouched. A []byte or json.RawMessage body is
+// sent as is, as JSON, and any other non-nil body is encoded to JSON. A nil
+// body, url.Values or *url.Values sends no body at all.
+//
+// Responses wrapped in reddit's {"json": {"errors": [], "data": {}}}
+// envelope are unwrapped: its errors are returned as an error, and its data
+// is what is decoded into the T.
+func PostJSON[T any](s *OAuthSession, body interface{}, urlformat string, urlvars ...interface{}) (T, error) {
+	if v, ok := formBody(body); ok {
+		if v.Get("api_type") == "" {
+			v.Set("api_type", "json")
+		}
+		body = v
+	}
+	return doJSON[T](s, POST, body, urlformat, urlvars...)
+}
+
+// PatchJSON performs a PATCH request against the OAuth API with s and
+// decodes the response into a T, see PostJSON.
+func PatchJSON[T any](s *OAuthSession, body interface{}, urlformat string, urlvars ...interface{}) (T, error) {
+	return doJSON[T](s, PATCH, body, urlformat, urlvars...)
+}
+
+// PutJSON performs a PUT request against the OAuth API with s and decodes
+// the response into a T, see PostJSON.
+func PutJSON[T any](s *OAuthSession, body interface{}, urlformat string, urlvars ...interface{}) (T, error) {
+	return doJSON[T](s, PUT, body, urlformat, urlvars...)
+}
+
+// DeleteJSON performs a DELETE request against the OAuth API with s and
+// decodes the response into a T, see PostJSON.
+func DeleteJSON[T any](s *OAuthSession, body interface{}, urlformat string, urlvars ...interface{}) (T, error) {
+	return doJSON[T](s, DELETE, body, urlformat, urlvars...)
+}
+
+// formBody returns a copy of body as form values, if it is some.
+func formBody(body interface{}) (url.Values, bool) {
+	var form url.Values
+	switch v := body.(type) {
+	case url.Values:
+		if v == nil {
+			return nil, false
+		}
+		form = v
+	case *url.Values:
+		if v == nil {
+			return nil, false
+		}
+		form = *v
+	default:
+		return nil, false
+	}
+	copied := make(url.Values, len(form))
+	for k, vs := range form {
+		copied[k] = append([]string(nil), vs...)
+	}
+	return copied, true
+}
+
+// noBody reports whether body is nil, including a nil url.Values or
+// *url.Values, which are sent as no body rather than as JSON null.
+func noBody(body interface{}) bool {
+	switch v := body.(type) {
+	case nil:
+		return true
+	case url.Values:
+		return v == nil
+	case *url.Values:
+		return v == nil
+	}
+	return false
+}
+
+func doJSON[T any](s *OAuthSession, action method, body interface{}, urlformat string, urlvars ...interface{}) (T, error) {
+	var v T
+	req := &oauthRequest{
+		url:    s.endpoints.ourl(urlformat, urlvars...),
+		action: action,
+	}
+	if form, ok := formBody(body); ok {
+		req.values = &form
+	} else if !noBody(body) {
+		switch b := body.(type) {
+		case json.RawMessage:
+			req.jsonBody = b
+		case []byte:
+			req.jsonBody = b
+		default:
+			var err error
+			if req.jsonBody, err = json.Marshal(body); err != nil {
+				return v, err
+			}
+		}
+	}
+
+	resp, err := s.send(req)
+	if err != nil {
+		return v, err
+	}
+	err = decodeJSON(resp.Bytes(), &v)
+	return v, err
+}
+
+// decodeJSON decodes a response into v, unwrapping reddit's JSON envelope.
+func decodeJSON(body []byte, v interface{}) error {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	var envelope struct {
+		JSON *struct {
+			Errors [][]string      `json:"errors"`
+			Data   json.RawMessage `json:"data"`
+		} `json:"json"`
+	}
+	if body[0] == '{' && json.Unmarshal(body, &envelope) == nil && envelope.JSON != nil {
+		if err := jsonErrors(envelope.JSON.Errors); err != nil {
+			return err
+		}
+		body = envelope.JSON.Data
+		if len(body) == 0 {
+			return nil
+		}
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,175 @@
+package geddit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type thing struct {
+		Name string `json:"name"`
+	}
+	tests := []struct {
+		body    string
+		want    thing
+		wantErr string
+	}{
+		{body: ``},
+		{body: "  \n"},
+		{body: `{"name": "plain"}`, want: thing{"plain"}},
+		{body: `{"json": {"errors": [], "data": {"name": "wrapped"}}}`, want: thing{"wrapped"}},
+		{body: `{"json": {"errors": []}}`},
+		{body: `{"json": {"data": {"name": "no errors"}}}`, want: thing{"no errors"}},
+		{
+			body:    `{"json": {"errors": [["RATELIMIT", "you are doing that too much", "ratelimit"], ["BAD"]], "data": {"name": "ignored"}}}`,
+			wantErr: "you are doing that too much, BAD",
+		},
+		{body: `{"name": `, wantErr: "unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		var got thing
+		err := decodeJSON([]byte(tt.body), &got)
+		switch {
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+			t.Errorf("decodeJSON(%q) = %v, want error %q", tt.body, err, tt.wantErr)
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("decodeJSON(%q) = %v", tt.body, err)
+		case got != tt.want:
+			t.Errorf("decodeJSON(%q) decoded %+v, want %+v", tt.body, got, tt.want)
+		}
+	}
+
+	// Bodies that are not envelopes are decoded as is.
+	var names []string
+	if err := decodeJSON([]byte(`["a", "b"]`), &names); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("decoded %v", names)
+	}
+}
+
+// capturedRequest is a request a captureJSON middleware answered.
+type capturedRequest struct {
+	method      string
+	contentType string
+	body        string
+}
+
+// captureJSON answers the requests for path with reply, recording them in
+// reqs, and passes the others on.
+func captureJSON(path, reply string, reqs *[]capturedRequest) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path != path {
+				return next.Do(req)
+			}
+			var body []byte
+			if req.Body != nil {
+				body, _ = ioutil.ReadAll(req.Body)
+			}
+			*reqs = append(*reqs, capturedRequest{req.Method, req.Header.Get("Content-Type"), string(body)})
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(reply))),
+				Request:    req,
+			}, nil
+		})
+	}
+}
+
+func TestJSONRequestBodies(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	var reqs []capturedRequest
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()),
+		WithMiddleware(captureJSON("/api/test", `{"json": {"errors": [], "data": {"ok": true}}}`, &reqs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		OK bool `json:"ok"`
+	}
+
+	form := url.Values{"thing_id": {"t3_l1"}}
+	pform := &url.Values{"thing_id": {"t3_l1"}}
+	var nilForm url.Values
+	bodies := []struct {
+		body        interface{}
+		contentType string
+		sent        string
+	}{
+		{form, "application/x-www-form-urlencoded", "api_type=json&thing_id=t3_l1"},
+		{pform, "application/x-www-form-urlencoded", "api_type=json&thing_id=t3_l1"},
+		{&nilForm, "application/x-www-form-urlencoded", "api_type=json"},
+		{url.Values{"api_type": {"raw"}}, "application/x-www-form-urlencoded", "api_type=raw"},
+		{map[string]string{"name": "gopher"}, "application/json", `{"name":"gopher"}`},
+		{[]byte(`{"raw":1}`), "application/json", `{"raw":1}`},
+		{nil, "", ""},
+		{(*url.Values)(nil), "", ""},
+		{url.Values(nil), "", ""},
+	}
+	for _, b := range bodies {
+		reqs = nil
+		got, err := PostJSON[result](s, b.body, "/api/test")
+		if err != nil {
+			t.Fatalf("%v: %v", b.body, err)
+		}
+		if !got.OK {
+			t.Errorf("%v: decoded %+v", b.body, got)
+		}
+		if len(reqs) != 1 {
+			t.Fatalf("%v: sent %d requests", b.body, len(reqs))
+		}
+		if r := reqs[0]; r.method != "POST" || r.contentType != b.contentType || strings.TrimSpace(r.body) != b.sent {
+			t.Errorf("%v: sent %+v, want Content-Type %q and body %q", b.body, r, b.contentType, b.sent)
+		}
+	}
+
+	// The caller's values are not modified.
+	if !reflect.DeepEqual(form, url.Values{"thing_id": {"t3_l1"}}) {
+		t.Errorf("PostJSON modified the url.Values to %v", form)
+	}
+	if !reflect.DeepEqual(*pform, url.Values{"thing_id": {"t3_l1"}}) {
+		t.Errorf("PostJSON modified the *url.Values to %v", *pform)
+	}
+	if nilForm != nil {
+		t.Errorf("PostJSON modified the nil *url.Values to %v", nilForm)
+	}
+
+	// Other verbs do not add api_type.
+	reqs = nil
+	if _, err := PutJSON[result](s, url.Values{"a": {"b"}}, "/api/test"); err != nil {
+		t.Fatal(err)
+	}
+	if r := reqs[0]; r.method != "PUT" || r.body != "a=b" {
+		t.Errorf("PutJSON sent %+v", r)
+	}
+
+	// Nil values are no body, whatever the verb, and GETs never have one.
+	var params *url.Values
+	verbs := map[string]func() (result, error){
+		"GET":    func() (result, error) { return GetJSON[result](s, params, "/api/test") },
+		"PATCH":  func() (result, error) { return PatchJSON[result](s, params, "/api/test") },
+		"PUT":    func() (result, error) { return PutJSON[result](s, params, "/api/test") },
+		"DELETE": func() (result, error) { return DeleteJSON[result](s, params, "/api/test") },
+	}
+	for verb, do := range verbs {
+		reqs = nil
+		if _, err := do(); err != nil {
+			t.Fatalf("%s: %v", verb, err)
+		}
+		if r := reqs[0]; r.method != verb || r.contentType != "" || r.body != "" {
+			t.Errorf("%s with nil values sent %+v", verb, r)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/google/go-querystring/query"
+)
+
+// thing is the kind/data wrapper reddit puts around most objects it returns.
+type thing struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// listingPage is a single page of a paginated reddit listing.
+type listingPage struct {
+	Kind string `json:"kind"`
+	Data struct {
+		Children []json.RawMessage `json:"children"`
+		After    string            `json:"after"`
+		Before   string            `json:"before"`
+	} `json:"data"`
+}
+
+// listingIterator walks a paginated listing through a Client, lazily fetching
+// the next page when the current one runs out. It is the engine behind the
+// exported, typed iterators such as RelationshipIterator.
+type listingIterator struct {
+	client  Client
+	path    string
+	params  url.Values
+	page    []thing
+	after   string
+	count   int
+	started bool
+	err     error
+}
+
+// newListingIterator returns an iterator over the listing at the given path,
+// starting from the position described by opts.
+func newListingIterator(c Client, opts ListingOptions, extra url.Values, urlformat string, urlvars ...interface{}) *listingIterator {
+	it := &listingIterator{
+		client: c,
+		path:   fmt.Sprintf(urlformat, urlvars...),
+		after:  opts.After,
+		count:  opts.Count,
+	}
+	v, err := query.Values(opts)
+	if err != nil {
+		it.err = err
+		return it
+	}
+	for key, vals := range extra {
+		v[key] = vals
+	}
+	it.params = v
+	return it
+}
+
+// fetch loads the next page of the listing.
+func (it *listingIterator) fetch() {
+	it.started = true
+	if it.after != "" {
+		it.params.Set("after", it.after)
+		it.params.Del("before")
+	}
+	if it.count > 0 {
+		it.params.Set("count", strconv.Itoa(it.count))
+	}
+
+	p := &listingPage{}
+	if err := getJSON(it.client, p, &it.params, "%s", it.path); err != nil {
+		it.err = err
+		return
+	}
+
+	it.page = make([]thing, 0, len(p.Data.Children))
+	for _, raw := range p.Data.Children {
+		// Some listings (e.g. UserList) hold bare objects instead of things.
+		t := thing{}
+		if err := json.Unmarshal(raw, &t); err != nil || t.Data == nil {
+			t = thing{Data: raw}
+		}
+		it.page = append(it.page, t)
+	}
+	it.count += len(p.Data.Children)
+	it.after = p.Data.After
+}
+
+// next returns the next item of the listing, fetching a new page if needed.
+// It returns false when the listing is exhausted or an error occurred.
+func (it *listingIterator) next() (thing, bool) {
+	for len(it.page) == 0 {
+		if it.err != nil || (it.started && it.after == "") {
+			return thing{}, false
+		}
+		it.fetch()
+	}
+	t := it.page[0]
+	it.page = it.page[1:]
+	return t, true
+}
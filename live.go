@@ -0,0 +1,447 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LiveThread represents a reddit live thread.
+type LiveThread struct {
+	ID              string  `json:"id"`
+	FullID          string  `json:"name"`
+	Title           string  `json:"title"`
+	Desc            string  `json:"description"`
+	DescHTML        string  `json:"description_html"`
+	Resources       string  `json:"resources"`
+	ResourcesHTML   string  `json:"resources_html"`
+	State           string  `json:"state"`
+	WebsocketURL    *string `json:"websocket_url"`
+	AnnouncementURL *string `json:"announcement_url"`
+	DateCreated     float64 `json:"created_utc"`
+	NumViewers      int     `json:"viewer_count"`
+	TotalViews      *int    `json:"total_views"`
+	IsNSFW          bool    `json:"nsfw"`
+}
+
+// String returns the string representation of a live thread.
+func (t *LiveThread) String() string {
+	return fmt.Sprintf("%s (%s)", t.Title, t.State)
+}
+
+// LiveUpdate represents a single update posted to a live thread.
+type LiveUpdate struct {
+	ID          string  `json:"id"`
+	FullID      string  `json:"name"`
+	Author      string  `json:"author"`
+	Body        string  `json:"body"`
+	BodyHTML    string  `json:"body_html"`
+	DateCreated float64 `json:"created_utc"`
+	IsStricken  bool    `json:"stricken"`
+}
+
+// String returns the string representation of a live update.
+func (u *LiveUpdate) String() string {
+	return fmt.Sprintf("%s: %s", u.Author, u.Body)
+}
+
+// liveUpdateID returns the fullname reddit expects to identify an update.
+func liveUpdateID(id string) string {
+	if strings.HasPrefix(id, "LiveUpdate_") {
+		return id
+	}
+	return "LiveUpdate_" + id
+}
+
+// LiveUpdateIterator iterates over the updates of a live thread, newest
+// first, in the same fashion as RelationshipIterator.
+type LiveUpdateIterator struct {
+	iter *listingIterator
+	cur  *LiveUpdate
+	err  error
+}
+
+// Next advances the iterator to the next update. It returns false when
+// there are no more updates or an error occurred.
+func (it *LiveUpdateIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	t, ok := it.iter.next()
+	if !ok {
+		it.err = it.iter.err
+		return false
+	}
+	u := &LiveUpdate{}
+	if it.err = json.Unmarshal(t.Data, u); it.err != nil {
+		return false
+	}
+	it.cur = u
+	return true
+}
+
+// Update returns the update the iterator currently points to.
+func (it *LiveUpdateIterator) Update() *LiveUpdate {
+	return it.cur
+}
+
+// After returns the value to pass as ListingOptions.After to resume the
+// listing after the last page fetched.
+func (it *LiveUpdateIterator) After() string {
+	return it.iter.after
+}
+
+// Err returns the first error encountered by the iterator.
+func (it *LiveUpdateIterator) Err() error {
+	return it.err
+}
+
+// CreateLiveThread creates a new live thread and returns its ID.
+func (s *OAuthSession) CreateLiveThread(title, description, resources string, nsfw bool) (string, error) {
+	if err := s.requireScope(SubmitScope); err != nil {
+		return "", err
+	}
+	data, err := s.apiPost(&url.Values{
+		"title":       {title},
+		"description": {description},
+		"resources":   {resources},
+		"nsfw":        {strconv.FormatBool(nsfw)},
+	}, "/api/live/create")
+	if err != nil {
+		return "", err
+	}
+
+	r := &struct {
+		ID string `json:"id"`
+	}{}
+	if err = json.Unmarshal(data, r); err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+// LiveThread returns information about a live thread.
+func (s *OAuthSession) LiveThread(id string) (*LiveThread, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/live/%s/about", id)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data LiveThread `json:"data"`
+	}
+	r := &Response{}
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+	return &r.Data, nil
+}
+
+// LiveUpdates returns an iterator over the updates of a live thread, newest
+// first.
+func (s *OAuthSession) LiveUpdates(thread string, params ListingOptions) *LiveUpdateIterator {
+	if err := s.requireScope(ReadScope); err != nil {
+		return &LiveUpdateIterator{err: err}
+	}
+	return &LiveUpdateIterator{
+		iter: newListingIterator(s, params, nil, "/live/%s", thread),
+	}
+}
+
+// PostLiveUpdate posts a new update to a live thread.
+func (s *OAuthSession) PostLiveUpdate(thread, body string) error {
+	if err := s.requireScope(SubmitScope); err != nil {
+		return err
+	}
+	_, err := s.apiPost(&url.Values{
+		"body": {body},
+	}, "/api/live/%s/update", thread)
+	return err
+}
+
+// StrikeLiveUpdate marks an update of a live thread as incorrect.
+func (s *OAuthSession) StrikeLiveUpdate(thread, id string) error {
+	if err := s.requireScope(EditScope); err != nil {
+		return err
+	}
+	_, err := s.apiPost(&url.Values{
+		"id": {liveUpdateID(id)},
+	}, "/api/live/%s/strike_update", thread)
+	return err
+}
+
+// DeleteLiveUpdate deletes an update of a live thread.
+func (s *OAuthSession) DeleteLiveUpdate(thread, id string) error {
+	if err := s.requireScope(EditScope); err != nil {
+		return err
+	}
+	_, err := s.apiPost(&url.Values{
+		"id": {liveUpdateID(id)},
+	}, "/api/live/%s/delete_update", thread)
+	return err
+}
+
+// CloseLiveThread permanently closes a live thread.
+func (s *OAuthSession) CloseLiveThread(thread string) error {
+	if err := s.requireScope(LiveManageScope); err != nil {
+		return err
+	}
+	_, err := s.apiPost(nil, "/api/live/%s/close_thread", thread)
+	return err
+}
+
+// InviteLiveContributor invites a user to contribute to a live thread.
+// permissions is in reddit's format, e.g. "+all" or "-all,+update".
+func (s *OAuthSession) InviteLiveContributor(thread, username, permissions string) error {
+	if err := s.requireScope(LiveManageScope); err != nil {
+		return err
+	}
+	_, err := s.apiPost(&url.Values{
+		"name":        {username},
+		"permissions": {permissions},
+		"type":        {"liveupdate_contributor_invite"},
+	}, "/api/live/%s/invite_contributor", thread)
+	return err
+}
+
+// AcceptLiveContributorInvite accepts a pending invitation to contribute to
+// a live thread.
+func (s *OAuthSession) AcceptLiveContributorInvite(thread string) error {
+	if err := s.requireScope(LiveManageScope); err != nil {
+		return err
+	}
+	_, err := s.apiPost(nil, "/api/live/%s/accept_contributor_invite", thread)
+	return err
+}
+
+// LiveStream delivers the updates posted to a live thread as they appear.
+// Updates are sent oldest first. Polling errors are sent on Errors when
+// someone is receiving from it and dropped otherwise; the stream keeps
+// polling until Close is called.
+type LiveStream struct {
+	Updates <-chan *LiveUpdate
+	Errors  <-chan error
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// Close stops the stream and closes its Updates channel.
+func (ls *LiveStream) Close() {
+	ls.once.Do(func() { close(ls.stop) })
+}
+
+// LiveUpdateStream polls a live thread every interval and streams the
+// updates posted after the call. Each poll pages through all the updates
+// posted since the last one streamed, however many there are. reddit has
+// nothing to page from once that update is deleted, so after a few empty
+// polls in a row the stream polls the newest updates instead, skipping the
+// ones it already streamed.
+func (s *OAuthSession) LiveUpdateStream(thread string, interval time.Duration) *LiveStream {
+	updates := make(chan *LiveUpdate)
+	errs := make(chan error)
+	ls := &LiveStream{
+		Updates: updates,
+		Errors:  errs,
+		stop:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(updates)
+
+		// cursor is the fullname of the newest update seen, empty while the
+		// thread has none. empty counts the polls in a row that found
+		// nothing after it.
+		var cursor string
+		started := false
+		empty := 0
+		seen := newLiveSeen()
+		limit := strconv.Itoa(liveUpdatePageSize)
+		for {
+			var (
+				latest []*LiveUpdate
+				err    error
+			)
+			switch {
+			case !started:
+				if latest, err = s.liveUpdatePage(thread, url.Values{"limit": {limit}}); err == nil {
+					started = true
+					for i := len(latest) - 1; i >= 0; i-- {
+						seen.add(latest[i].FullID)
+					}
+					if len(latest) > 0 {
+						cursor = latest[0].FullID
+					}
+					latest = nil
+				}
+			case cursor != "" && empty >= liveStaleCursorPolls:
+				empty = 0
+				if latest, err = s.liveUpdatePage(thread, url.Values{"limit": {limit}}); err == nil {
+					cursor = ""
+					if len(latest) > 0 {
+						cursor = latest[0].FullID
+					}
+					for i, j := 0, len(latest)-1; i < j; i, j = i+1, j-1 {
+						latest[i], latest[j] = latest[j], latest[i]
+					}
+				}
+			default:
+				latest, err = s.liveUpdatesSince(thread, cursor)
+				if err == nil && len(latest) == 0 {
+					empty++
+				} else {
+					empty = 0
+				}
+			}
+			for _, u := range latest {
+				if seen.has(u.FullID) {
+					continue
+				}
+				select {
+				case updates <- u:
+					cursor = u.FullID
+					seen.add(u.FullID)
+				case <-ls.stop:
+					return
+				}
+			}
+			if err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-ls.stop:
+				return
+			}
+		}
+	}()
+
+	return ls
+}
+
+// liveStaleCursorPolls is the number of empty polls in a row after which a
+// live stream stops trusting its cursor.
+const liveStaleCursorPolls = 3
+
+// liveSeen remembers the fullnames of the latest updates a live stream
+// streamed, as many as fit in a page.
+type liveSeen struct {
+	ids   map[string]bool
+	order []string
+}
+
+func newLiveSeen() *liveSeen {
+	return &liveSeen{ids: make(map[string]bool)}
+}
+
+func (s *liveSeen) has(id string) bool {
+	return s.ids[id]
+}
+
+func (s *liveSeen) add(id string) {
+	if s.ids[id] {
+		return
+	}
+	s.ids[id] = true
+	s.order = append(s.order, id)
+	if len(s.order) > liveUpdatePageSize {
+		delete(s.ids, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// liveUpdatePageSize is the number of updates fetched per page while
+// streaming, the most reddit returns.
+const liveUpdatePageSize = 100
+
+// liveUpdatesSince returns the updates of a live thread newer than the one
+// with the fullname cursor, or all of them if cursor is empty, oldest first.
+// On error it returns those fetched so far, which follow cursor.
+func (s *OAuthSession) liveUpdatesSince(thread, cursor string) ([]*LiveUpdate, error) {
+	limit := strconv.Itoa(liveUpdatePageSize)
+	var since []*LiveUpdate
+	if cursor == "" {
+		// Nothing to page forward from: walk the whole thread back from the
+		// newest update, then put it in order.
+		after := ""
+		for {
+			page, err := s.liveUpdatePage(thread, url.Values{"limit": {limit}, "after": {after}})
+			if err != nil {
+				return nil, err
+			}
+			since = append(since, page...)
+			if len(page) < liveUpdatePageSize {
+				break
+			}
+			after = page[len(page)-1].FullID
+		}
+		for i, j := 0, len(since)-1; i < j; i, j = i+1, j-1 {
+			since[i], since[j] = since[j], since[i]
+		}
+		return since, nil
+	}
+
+	// Pages fetched with before hold the updates right after cursor, newest
+	// first.
+	for {
+		page, err := s.liveUpdatePage(thread, url.Values{"limit": {limit}, "before": {cursor}})
+		if err != nil {
+			return since, err
+		}
+		for i := len(page) - 1; i >= 0; i-- {
+			since = append(since, page[i])
+		}
+		if len(page) < liveUpdatePageSize {
+			return since, nil
+		}
+		cursor = page[0].FullID
+	}
+}
+
+// liveUpdatePage returns a page of the updates of a live thread, newest
+// first.
+func (s *OAuthSession) liveUpdatePage(thread string, params url.Values) ([]*LiveUpdate, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	if params.Get("after") == "" {
+		params.Del("after")
+	}
+	body, err := s.Get(&params, "/live/%s", thread)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data struct {
+			Children []struct {
+				Data *LiveUpdate `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+	r := &Response{}
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+
+	page := make([]*LiveUpdate, len(r.Data.Children))
+	for i, child := range r.Data.Children {
+		page[i] = child.Data
+	}
+	return page, nil
+}
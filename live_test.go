@@ -0,0 +1,203 @@
+package geddit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// fakeLiveThread serves the updates listing of a live thread, which
+// geddittest does not model, paging it like reddit does.
+type fakeLiveThread struct {
+	mu       sync.Mutex
+	updates  []*LiveUpdate // oldest first
+	posted   int
+	requests int
+}
+
+func (f *fakeLiveThread) served() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *fakeLiveThread) post(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := 0; i < n; i++ {
+		id := strconv.Itoa(f.posted)
+		f.posted++
+		f.updates = append(f.updates, &LiveUpdate{ID: id, FullID: "LiveUpdate_" + id, Body: id})
+	}
+}
+
+// delete deletes the update with the given fullname.
+func (f *fakeLiveThread) delete(fullID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i := f.index(fullID); i >= 0 {
+		f.updates = append(f.updates[:i], f.updates[i+1:]...)
+	}
+}
+
+func (f *fakeLiveThread) index(fullID string) int {
+	for i, u := range f.updates {
+		if u.FullID == fullID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *fakeLiveThread) middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasPrefix(req.URL.Path, "/live/") {
+			return next.Do(req)
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests++
+		q := req.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		lo, hi := 0, len(f.updates)
+		if before := q.Get("before"); before != "" {
+			// Like reddit, serve nothing before a deleted update.
+			lo = f.index(before) + 1
+			if lo == 0 {
+				lo = hi
+			}
+			if hi > lo+limit {
+				hi = lo + limit
+			}
+		} else {
+			if after := q.Get("after"); after != "" {
+				hi = f.index(after)
+			}
+			if lo < hi-limit {
+				lo = hi - limit
+			}
+		}
+
+		type child struct {
+			Kind string      `json:"kind"`
+			Data *LiveUpdate `json:"data"`
+		}
+		var listing struct {
+			Data struct {
+				Children []child `json:"children"`
+			} `json:"data"`
+		}
+		for i := hi - 1; i >= lo; i-- {
+			listing.Data.Children = append(listing.Data.Children, child{"LiveUpdate", f.updates[i]})
+		}
+		b, err := json.Marshal(listing)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			Request:    req,
+		}, nil
+	})
+}
+
+func TestLiveUpdateStreamKeepsEveryUpdate(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	thread := &fakeLiveThread{}
+	thread.post(3)
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret,
+		WithHTTPClient(srv.Client()), WithMiddleware(thread.middleware))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls := s.LiveUpdateStream("t1", 5*time.Millisecond)
+	defer ls.Close()
+	// Let the stream see the existing updates, then post several pages'
+	// worth at once.
+	for thread.served() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	thread.post(2*liveUpdatePageSize + 50)
+
+	timeout := time.After(5 * time.Second)
+	for want := 3; want < 3+2*liveUpdatePageSize+50; want++ {
+		select {
+		case u := <-ls.Updates:
+			if u.ID != strconv.Itoa(want) {
+				t.Fatalf("got update %s, want %d", u.ID, want)
+			}
+		case err := <-ls.Errors:
+			t.Fatal(err)
+		case <-timeout:
+			t.Fatalf("timed out waiting for update %d", want)
+		}
+	}
+}
+
+func TestLiveUpdateStreamSurvivesDeletedCursor(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	thread := &fakeLiveThread{}
+	thread.post(3)
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret,
+		WithHTTPClient(srv.Client()), WithMiddleware(thread.middleware))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls := s.LiveUpdateStream("t1", time.Millisecond)
+	defer ls.Close()
+	for thread.served() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The stream's cursor is the newest update; once it is deleted, polls
+	// before it come back empty.
+	thread.delete("LiveUpdate_2")
+	thread.post(3)
+
+	timeout := time.After(5 * time.Second)
+	for _, want := range []string{"3", "4", "5"} {
+		select {
+		case u := <-ls.Updates:
+			if u.ID != want {
+				t.Fatalf("got update %s, want %s", u.ID, want)
+			}
+		case err := <-ls.Errors:
+			t.Fatal(err)
+		case <-timeout:
+			t.Fatalf("timed out waiting for update %s", want)
+		}
+	}
+	// Later polls of the newest updates stream none of them again.
+	served := thread.served()
+	for thread.served() < served+2*liveStaleCursorPolls {
+		select {
+		case u := <-ls.Updates:
+			t.Fatalf("got update %s again", u.ID)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	thread.post(1)
+	select {
+	case u := <-ls.Updates:
+		if u.ID != "6" {
+			t.Fatalf("got update %s, want 6", u.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for update 6")
+	}
+}
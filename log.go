@@ -0,0 +1,153 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// sensitiveParams are the query parameters whose values never get logged.
+var sensitiveParams = map[string]bool{
+	"access_token":  true,
+	"client_secret": true,
+	"code":          true,
+	"curpass":       true,
+	"passwd":        true,
+	"password":      true,
+	"refresh_token": true,
+	"token":         true,
+	"uh":            true,
+}
+
+// requestLogger emits debug events about the requests a session performs.
+// A nil *requestLogger logs nothing.
+type requestLogger struct {
+	logger *slog.Logger
+	bodies bool
+}
+
+// enabled reports whether debug events would be logged at all.
+func (l *requestLogger) enabled() bool {
+	return l != nil && l.logger != nil && l.logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+// logResponse logs the outcome of a request.
+func (l *requestLogger) logResponse(method, rawurl string, resp *http.Response, start time.Time) {
+	if !l.enabled() {
+		return
+	}
+	attrs := []interface{}{
+		slog.String("method", method),
+		slog.String("url", redactURL(rawurl)),
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("duration", time.Since(start)),
+	}
+	for _, h := range []string{"X-Ratelimit-Used", "X-Ratelimit-Remaining", "X-Ratelimit-Reset"} {
+		if v := resp.Header.Get(h); v != "" {
+			attrs = append(attrs, slog.String(h, v))
+		}
+	}
+	l.logger.Debug("reddit response", attrs...)
+}
+
+// logError logs a request that failed before a response was received.
+func (l *requestLogger) logError(method, rawurl string, err error, start time.Time) {
+	if !l.enabled() {
+		return
+	}
+	l.logger.Debug("reddit request failed",
+		slog.String("method", method),
+		slog.String("url", redactURL(rawurl)),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("error", err.Error()),
+	)
+}
+
+// dumpsBodies reports whether response bodies are logged.
+func (l *requestLogger) dumpsBodies() bool {
+	return l.enabled() && l.bodies
+}
+
+// logBody logs a response body, if body dumping was enabled.
+func (l *requestLogger) logBody(rawurl string, body []byte) {
+	if !l.dumpsBodies() {
+		return
+	}
+	l.logger.Debug("reddit response body",
+		slog.String("url", redactURL(rawurl)),
+		slog.String("body", string(body)),
+	)
+}
+
+// redactURL returns rawurl with the values of sensitive query parameters
+// replaced, so it can be logged.
+func redactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "<unparsable url>"
+	}
+	u.User = nil
+	q := u.Query()
+	redacted := false
+	for key := range q {
+		if sensitiveParams[key] {
+			q.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+	if redacted {
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// SetLogger makes the session log a debug event for every request it
+// performs, with the method, URL, status, duration and rate limit headers.
+// Secrets are left out. Passing nil disables logging, which is the default.
+func (s *Session) SetLogger(logger *slog.Logger) {
+	s.log = newRequestLogger(s.log, logger)
+}
+
+// SetLogBodies makes the session's logger also dump every response body.
+// Bodies may contain private data, so this is meant for debugging only.
+func (s *Session) SetLogBodies(enabled bool) {
+	s.log = setLogBodies(s.log, enabled)
+}
+
+// SetLogger makes the session log a debug event for every request it
+// performs, including token requests, with the method, URL, status, duration
+// and rate limit headers. Secrets are left out. Passing nil disables
+// logging, which is the default.
+func (s *OAuthSession) SetLogger(logger *slog.Logger) {
+	s.log = newRequestLogger(s.log, logger)
+}
+
+// SetLogBodies makes the session's logger also dump every response body.
+// Bodies may contain private data, so this is meant for debugging only.
+func (s *OAuthSession) SetLogBodies(enabled bool) {
+	s.log = setLogBodies(s.log, enabled)
+}
+
+// newRequestLogger returns a copy of old using logger. Copies are made so
+// that requests in flight keep the configuration they started with.
+func newRequestLogger(old *requestLogger, logger *slog.Logger) *requestLogger {
+	l := &requestLogger{logger: logger}
+	if old != nil {
+		l.bodies = old.bodies
+	}
+	return l
+}
+
+func setLogBodies(old *requestLogger, enabled bool) *requestLogger {
+	l := &requestLogger{bodies: enabled}
+	if old != nil {
+		l.logger = old.logger
+	}
+	return l
+}
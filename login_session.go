@@ -6,9 +6,11 @@
 package geddit
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -19,6 +21,10 @@ import (
 
 // LoginSession represents an HTTP session with reddit.com --
 // all authenticated API calls are methods bound to this type.
+//
+// A LoginSession created by NewScriptLoginSession makes its calls through
+// an OAuthSession instead of a session cookie, which reddit no longer
+// hands out; see NewScriptLoginSession.
 type LoginSession struct {
 	username  string
 	password  string
@@ -26,29 +32,42 @@ type LoginSession struct {
 	cookie    *http.Cookie
 	modhash   string `json:"modhash"`
 	Session
+
+	// oauth, if set, is the session all calls are made with.
+	oauth *OAuthSession
 }
 
 // NewLoginSession creates a new session for those who want to log into a
 // reddit account.
+//
+// Deprecated: reddit no longer supports logging in with a password and a
+// session cookie. Use NewScriptLoginSession, which keeps the LoginSession
+// API.
 func NewLoginSession(username, password, useragent string) (*LoginSession, error) {
 	session := &LoginSession{
 		username:  username,
 		password:  password,
 		useragent: useragent,
-		Session:   Session{useragent},
+		Session:   Session{useragent: useragent},
 	}
 
-	loginURL := rurl("/api/login/%s", username)
 	postValues := url.Values{
 		"user":     {username},
 		"passwd":   {password},
 		"api_type": {"json"},
 	}
-	resp, err := http.PostForm(loginURL, postValues)
-	defer resp.Body.Close()
+	loginURL := session.endpoints.rurl("/api/login/%s", url.PathEscape(username))
+	req, err := http.NewRequest("POST", loginURL, strings.NewReader(postValues.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", useragent)
+	resp, err := session.doer().Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, errors.New(resp.Status)
@@ -89,15 +108,195 @@ func NewLoginSession(username, password, useragent string) (*LoginSession, error
 	return session, nil
 }
 
+// NewScriptLoginSession creates a LoginSession for the given account that
+// makes its calls through an OAuthSession of a reddit "script" app, created
+// with NewOAuthSession and opts. It replaces NewLoginSession: callers keep
+// using the LoginSession methods, which are then subject to the scopes of
+// the app's token, and have no need for captchas.
+func NewScriptLoginSession(username, password, useragent, clientID, clientSecret string, opts ...OAuthOption) (*LoginSession, error) {
+	o, err := NewOAuthSession(username, password, useragent, clientID, clientSecret, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginSession{
+		username:  username,
+		password:  password,
+		useragent: useragent,
+		Session:   Session{useragent: useragent, endpoints: o.endpoints},
+		oauth:     o,
+	}, nil
+}
+
+// OAuth returns the OAuthSession a session created by NewScriptLoginSession
+// makes its calls with, for the API LoginSession lacks, or nil for a cookie
+// session.
+func (s LoginSession) OAuth() *OAuthSession {
+	return s.oauth
+}
+
+// requireScope checks scope against the token of an OAuth-backed session.
+func (s LoginSession) requireScope(scope string) error {
+	if s.oauth != nil {
+		return s.oauth.requireScope(scope)
+	}
+	return nil
+}
+
+// The methods below shadow the ones of the embedded Session, so that an
+// OAuth-backed session reads through its OAuthSession and is configured
+// along with it.
+
+// DefaultFrontpage returns the submissions on the default reddit frontpage,
+// or on the user's frontpage for an OAuth-backed session.
+func (s LoginSession) DefaultFrontpage(sort popularitySort, params ListingOptions) ([]*Submission, error) {
+	if s.oauth != nil {
+		return s.oauth.DefaultFrontpage(sort, params)
+	}
+	return s.Session.DefaultFrontpage(sort, params)
+}
+
+// AboutSubreddit returns a subreddit for the given subreddit name.
+func (s LoginSession) AboutSubreddit(subreddit string) (*Subreddit, error) {
+	if s.oauth != nil {
+		return s.oauth.AboutSubreddit(subreddit)
+	}
+	return s.Session.AboutSubreddit(subreddit)
+}
+
+// Comments returns the comments for a given Submission.
+func (s LoginSession) Comments(h *Submission) ([]*Comment, error) {
+	if s.oauth != nil {
+		return s.oauth.Comments(h)
+	}
+	return s.Session.Comments(h)
+}
+
+// Use appends middlewares to the session's chain.
+func (s *LoginSession) Use(mw ...Middleware) {
+	s.Session.Use(mw...)
+	if s.oauth != nil {
+		s.oauth.Use(mw...)
+	}
+}
+
+// SetHTTPClient replaces the Doer at the end of the session's middleware
+// chain, see Session.SetHTTPClient.
+func (s *LoginSession) SetHTTPClient(c Doer) {
+	s.Session.SetHTTPClient(c)
+	if s.oauth != nil {
+		s.oauth.SetHTTPClient(c)
+	}
+}
+
+// SetCache makes the session serve GET requests from c, see
+// Session.SetCache.
+func (s *LoginSession) SetCache(c Cache, opts CacheOptions) {
+	s.Session.SetCache(c, opts)
+	if s.oauth != nil {
+		s.oauth.SetCache(c, opts)
+	}
+}
+
+// SetCoalescing makes concurrent identical GET requests of the session
+// share a single HTTP request, see Session.SetCoalescing.
+func (s *LoginSession) SetCoalescing(enabled bool) {
+	s.Session.SetCoalescing(enabled)
+	if s.oauth != nil {
+		s.oauth.SetCoalescing(enabled)
+	}
+}
+
+// SetLogger makes the session log a debug event for every request it
+// performs, see Session.SetLogger.
+func (s *LoginSession) SetLogger(logger *slog.Logger) {
+	s.Session.SetLogger(logger)
+	if s.oauth != nil {
+		s.oauth.SetLogger(logger)
+	}
+}
+
+// SetLogBodies makes the session's logger also dump every response body,
+// see Session.SetLogBodies.
+func (s *LoginSession) SetLogBodies(enabled bool) {
+	s.Session.SetLogBodies(enabled)
+	if s.oauth != nil {
+		s.oauth.SetLogBodies(enabled)
+	}
+}
+
+// SetEndpoints makes the session send its requests to, and build URLs
+// from, the given endpoints, see Session.SetEndpoints.
+func (s *LoginSession) SetEndpoints(e Endpoints) {
+	s.Session.SetEndpoints(e)
+	if s.oauth != nil {
+		s.oauth.SetEndpoints(e)
+	}
+}
+
+// ResumeLoginSession returns a session using the cookie and modhash saved
+// in store under key, or under the username if key is empty. If none are
+// saved or they have expired, it logs in like NewLoginSession and saves the
+// new ones.
+func ResumeLoginSession(username, password, useragent string, store TokenStore, key string) (*LoginSession, error) {
+	if key == "" {
+		key = username
+	}
+	t, err := store.Load(key)
+	if err == nil && t.Cookie != "" && !t.Expired() {
+		return &LoginSession{
+			username:  username,
+			password:  password,
+			useragent: useragent,
+			cookie:    &http.Cookie{Name: "reddit_session", Value: t.Cookie, Expires: t.Expiry},
+			modhash:   t.Modhash,
+			Session:   Session{useragent: useragent},
+		}, nil
+	}
+	if err != nil && err != ErrNoToken {
+		return nil, err
+	}
+
+	session, err := NewLoginSession(username, password, useragent)
+	if err != nil {
+		return nil, err
+	}
+	if err = store.Save(key, session.Token()); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Token returns the session's cookie and modhash, to be saved in a
+// TokenStore, or its OAuth token if it was created by NewScriptLoginSession.
+func (s LoginSession) Token() *Token {
+	if s.oauth != nil {
+		return s.oauth.Token()
+	}
+	t := &Token{Modhash: s.modhash}
+	if s.cookie != nil {
+		t.Cookie = s.cookie.Value
+		t.Expiry = s.cookie.Expires
+	}
+	return t
+}
+
 // Clear clears all session cookies and updates the current session with a new one.
+// An OAuth-backed session revokes its token instead, getting a new one on its
+// next call.
 func (s LoginSession) Clear() error {
+	if s.oauth != nil {
+		return s.oauth.RevokeToken()
+	}
 	req := &request{
-		url: BASE_URL + "/api/clear_sessions",
+		url: s.endpoints.rurl("/api/clear_sessions"),
 		values: &url.Values{
 			"curpass": {s.password},
 			"uh":      {s.modhash},
 		},
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 	body, err := req.getResponse()
 	if err != nil {
@@ -112,23 +311,24 @@ func (s LoginSession) Clear() error {
 
 // Frontpage returns the submissions on the logged-in user's personal frontpage.
 func (s LoginSession) Frontpage(sort popularitySort, params ListingOptions) ([]*Submission, error) {
+	if s.oauth != nil {
+		return s.oauth.DefaultFrontpage(sort, params)
+	}
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 
-	redditUrl := rurl("/%s/.json?%s", sort, v.Encode())
+	redditUrl := s.endpoints.rurl("/%s/.json?%s", sort, v.Encode())
 
 	req := request{
 		url:       redditUrl,
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
-	body, err := req.getResponse()
-	if err != nil {
-		return nil, err
-	}
-
 	type Response struct {
 		Data struct {
 			Children []struct {
@@ -137,13 +337,14 @@ func (s LoginSession) Frontpage(sort popularitySort, params ListingOptions) ([]*
 		}
 	}
 	r := &Response{}
-	err = json.NewDecoder(body).Decode(r)
-	if err != nil {
+	if err = req.decode(r); err != nil {
 		return nil, err
 	}
 
 	submissions := make([]*Submission, len(r.Data.Children))
+	web := s.Endpoints().WWW
 	for i, child := range r.Data.Children {
+		child.Data.web = web
 		submissions[i] = child.Data
 	}
 
@@ -152,30 +353,31 @@ func (s LoginSession) Frontpage(sort popularitySort, params ListingOptions) ([]*
 
 // SubredditSubmissions returns the submissions on the given subreddit.
 func (s LoginSession) SubredditSubmissions(subreddit string, sort popularitySort, params ListingOptions) ([]*Submission, error) {
+	if s.oauth != nil {
+		return s.oauth.SubredditSubmissions(subreddit, sort, params)
+	}
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 
-	baseUrl := BASE_URL
+	baseUrl := s.Endpoints().WWW
 
 	// If subbreddit given, add to URL
 	if subreddit != "" {
 		baseUrl += "/r/" + subreddit
 	}
 
-	redditUrl := fmt.Sprintf(baseUrl+"/%s.json?%s", sort, v.Encode())
+	redditUrl := baseUrl + fmt.Sprintf("/%s.json?%s", sort, v.Encode())
 
 	req := request{
 		url:       redditUrl,
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
-	body, err := req.getResponse()
-	if err != nil {
-		return nil, err
-	}
-
 	type Response struct {
 		Data struct {
 			Children []struct {
@@ -185,25 +387,96 @@ func (s LoginSession) SubredditSubmissions(subreddit string, sort popularitySort
 	}
 
 	r := new(Response)
-	err = json.NewDecoder(body).Decode(r)
-	if err != nil {
+	if err = req.decode(r); err != nil {
 		return nil, err
 	}
 
 	submissions := make([]*Submission, len(r.Data.Children))
+	web := s.Endpoints().WWW
 	for i, child := range r.Data.Children {
+		child.Data.web = web
 		submissions[i] = child.Data
 	}
 
 	return submissions, nil
 }
 
+// Get fetches the JSON version of a reddit.com page as the logged-in user.
+// Together with Post it makes LoginSession a Client.
+func (s LoginSession) Get(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
+	if s.oauth != nil {
+		return s.oauth.Get(params, urlformat, urlvars...)
+	}
+	req := &request{
+		url:       s.endpoints.jsonURL(params, urlformat, urlvars...),
+		cookie:    s.cookie,
+		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
+	}
+	return req.getResponse()
+}
+
+// getJSON fetches the JSON version of a reddit.com page as the logged-in
+// user and decodes it into v as it is read.
+func (s LoginSession) getJSON(v interface{}, params *url.Values, urlformat string, urlvars ...interface{}) error {
+	if s.oauth != nil {
+		return s.oauth.getJSON(v, params, urlformat, urlvars...)
+	}
+	req := &request{
+		url:       s.endpoints.jsonURL(params, urlformat, urlvars...),
+		cookie:    s.cookie,
+		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
+	}
+	return req.decode(v)
+}
+
+// Post posts params to a reddit.com API endpoint as the logged-in user.
+func (s LoginSession) Post(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
+	if s.oauth != nil {
+		return s.oauth.Post(params, urlformat, urlvars...)
+	}
+	values := url.Values{}
+	if params != nil {
+		for k, v := range *params {
+			values[k] = v
+		}
+	}
+	values.Set("uh", s.modhash)
+	req := &request{
+		url:       s.endpoints.rurl(urlformat, urlvars...),
+		values:    &values,
+		cookie:    s.cookie,
+		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
+	}
+	return req.getResponse()
+}
+
+// AboutRedditor returns a Redditor for the given username, bound to the
+// logged-in session.
+func (s LoginSession) AboutRedditor(username string) (*Redditor, error) {
+	return aboutRedditor(s, username)
+}
+
 // Me returns an up-to-date redditor object of the logged-in user.
 func (s LoginSession) Me() (*Redditor, error) {
+	if s.oauth != nil {
+		return s.oauth.Me()
+	}
 	req := &request{
-		url:       BASE_URL + "/api/me.json",
+		url:       s.endpoints.rurl("/api/me.json"),
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 	body, err := req.getResponse()
 	if err != nil {
@@ -218,11 +491,17 @@ func (s LoginSession) Me() (*Redditor, error) {
 	if err != nil {
 		return nil, err
 	}
+	r.Data.client = s
+	r.Data.self = true
 
 	return &r.Data, nil
 }
 
+// Submit submits a link or text post.
 func (s LoginSession) Submit(ns *newSubmission) error {
+	if s.oauth != nil {
+		return s.oauth.Submit(ns)
+	}
 
 	var kind string
 
@@ -233,7 +512,7 @@ func (s LoginSession) Submit(ns *newSubmission) error {
 	}
 
 	req := &request{
-		url: BASE_URL + "/api/submit",
+		url: s.endpoints.rurl("/api/submit"),
 		values: &url.Values{
 			"title":       {ns.Title},
 			"url":         {ns.Content},
@@ -249,6 +528,9 @@ func (s LoginSession) Submit(ns *newSubmission) error {
 		},
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 
 	body, err := req.getResponse()
@@ -263,8 +545,11 @@ func (s LoginSession) Submit(ns *newSubmission) error {
 
 // Vote either votes or rescinds a vote for a Submission or Comment.
 func (s LoginSession) Vote(v Voter, vote vote) error {
+	if s.oauth != nil {
+		return s.oauth.Vote(v, vote)
+	}
 	req := &request{
-		url: BASE_URL + "/api/vote",
+		url: s.endpoints.rurl("/api/vote"),
 		values: &url.Values{
 			"id":  {v.voteID()},
 			"dir": {string(vote)},
@@ -272,6 +557,9 @@ func (s LoginSession) Vote(v Voter, vote vote) error {
 		},
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 	body, err := req.getResponse()
 	if err != nil {
@@ -285,8 +573,11 @@ func (s LoginSession) Vote(v Voter, vote vote) error {
 
 // Reply posts a comment as a response to a Submission or Comment.
 func (s LoginSession) Reply(r Replier, comment string) error {
+	if s.oauth != nil {
+		return s.oauth.Reply(r, comment)
+	}
 	req := &request{
-		url: BASE_URL + "/api/comment",
+		url: s.endpoints.rurl("/api/comment"),
 		values: &url.Values{
 			"thing_id": {r.replyID()},
 			"text":     {comment},
@@ -294,6 +585,9 @@ func (s LoginSession) Reply(r Replier, comment string) error {
 		},
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 
 	body, err := req.getResponse()
@@ -310,14 +604,20 @@ func (s LoginSession) Reply(r Replier, comment string) error {
 
 // Delete deletes a Submission or Comment.
 func (s LoginSession) Delete(d Deleter) error {
+	if s.oauth != nil {
+		return s.oauth.Delete(d)
+	}
 	req := &request{
-		url: BASE_URL + "/api/del",
+		url: s.endpoints.rurl("/api/del"),
 		values: &url.Values{
 			"id": {d.deleteID()},
 			"uh": {s.modhash},
 		},
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 
 	body, err := req.getResponse()
@@ -332,12 +632,19 @@ func (s LoginSession) Delete(d Deleter) error {
 	return nil
 }
 
-// NeedsCaptcha returns true if captcha is required, false if it isn't
+// NeedsCaptcha returns true if captcha is required, false if it isn't.
+// OAuth-backed sessions never need one.
 func (s LoginSession) NeedsCaptcha() (bool, error) {
+	if s.oauth != nil {
+		return false, nil
+	}
 	req := &request{
-		url:       BASE_URL + "/api/needs_captcha.json",
+		url:       s.endpoints.rurl("/api/needs_captcha.json"),
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 
 	body, err := req.getResponse()
@@ -357,13 +664,19 @@ func (s LoginSession) NeedsCaptcha() (bool, error) {
 
 // NewCaptchaIden gets a new captcha iden from reddit
 func (s LoginSession) NewCaptchaIden() (string, error) {
+	if s.oauth != nil {
+		return "", errors.New("captchas are not used with OAuth")
+	}
 	req := &request{
-		url: BASE_URL + "/api/new_captcha",
+		url: s.endpoints.rurl("/api/new_captcha"),
 		values: &url.Values{
 			"api_type": {"json"},
 		},
 		cookie:    s.cookie,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 	body, err := req.getResponse()
 	if err != nil {
@@ -389,8 +702,21 @@ func (s LoginSession) NewCaptchaIden() (string, error) {
 	return r.JSON.Data.Iden, nil
 }
 
-// Listing returns a listing for an user
+// Listing returns a listing for an user. On OAuth-backed sessions it needs
+// the history scope, and the legacy "liked" and "disliked" listings are
+// fetched as "upvoted" and "downvoted", their OAuth API names.
 func (s LoginSession) Listing(username, listing string, sort popularitySort, after string) ([]*Submission, error) {
+	if err := s.requireScope(HistoryScope); err != nil {
+		return nil, err
+	}
+	if s.oauth != nil {
+		switch listing {
+		case "liked":
+			listing = "upvoted"
+		case "disliked":
+			listing = "downvoted"
+		}
+	}
 	values := &url.Values{}
 	if sort != "" {
 		values.Set("sort", string(sort))
@@ -398,17 +724,6 @@ func (s LoginSession) Listing(username, listing string, sort popularitySort, aft
 	if after != "" {
 		values.Set("after", after)
 	}
-	url := rurl("/user/%s/%s.json?%s", username, listing, values.Encode())
-	req := &request{
-		url:       url,
-		cookie:    s.cookie,
-		useragent: s.useragent,
-	}
-
-	body, err := req.getResponse()
-	if err != nil {
-		return nil, err
-	}
 
 	type Response struct {
 		Data struct {
@@ -419,13 +734,14 @@ func (s LoginSession) Listing(username, listing string, sort popularitySort, aft
 	}
 
 	r := &Response{}
-	err = json.NewDecoder(body).Decode(r)
-	if err != nil {
+	if err := s.getJSON(r, values, "/user/%s/%s", username, listing); err != nil {
 		return nil, err
 	}
 
 	submissions := make([]*Submission, len(r.Data.Children))
+	web := s.Endpoints().WWW
 	for i, child := range r.Data.Children {
+		child.Data.web = web
 		submissions[i] = child.Data
 	}
 
@@ -0,0 +1,145 @@
+package geddit
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func newTestScriptLoginSession(t *testing.T, grants *int32) (*LoginSession, *geddittest.Server) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	s, err := NewScriptLoginSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret,
+		WithHTTPClient(srv.Client()), WithMiddleware(tokenCounter(grants)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, srv
+}
+
+// submissionIDs returns the IDs of submissions.
+func submissionIDs(submissions []*Submission) []string {
+	ids := make([]string, len(submissions))
+	for i, s := range submissions {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func TestScriptLoginSessionReads(t *testing.T) {
+	var grants int32
+	s, _ := newTestScriptLoginSession(t, &grants)
+
+	front, err := s.Frontpage(DefaultPopularity, ListingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := submissionIDs(front); len(ids) != 2 || ids[0] != "l1" {
+		t.Errorf("got frontpage %v, want l1 and l2", ids)
+	}
+
+	me, err := s.Me()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Name != geddittest.Username || !me.self {
+		t.Errorf("got %+v from Me", me)
+	}
+
+	needsCaptcha, err := s.NeedsCaptcha()
+	if err != nil || needsCaptcha {
+		t.Errorf("got %v, %v from NeedsCaptcha, want false", needsCaptcha, err)
+	}
+	if n := atomic.LoadInt32(&grants); n != 1 {
+		t.Errorf("got %d token requests, want 1", n)
+	}
+}
+
+func TestScriptLoginSessionWrites(t *testing.T) {
+	var grants int32
+	s, srv := newTestScriptLoginSession(t, &grants)
+
+	if err := s.Submit(NewTextSubmission("golang", "Generics", "When?", true, &Captcha{})); err != nil {
+		t.Fatal(err)
+	}
+	links := srv.Links("golang")
+	posted := links[len(links)-1]
+	if posted.Title != "Generics" || posted.Author != geddittest.Username {
+		t.Fatalf("got submission %+v", posted)
+	}
+
+	if err := s.Vote(&Submission{FullID: "t3_l2"}, UpVote); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Vote(&Submission{FullID: "t3_l1"}, DownVote); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Vote(geddittest.Username, "t3_l2"); got != 1 {
+		t.Errorf("got vote %d on l2, want 1", got)
+	}
+
+	lists := []struct {
+		name string
+		get  func(popularitySort, string) ([]*Submission, error)
+		want []string
+	}{
+		{"MySubmitted", s.MySubmitted, []string{"l1", posted.ID}},
+		{"MyLiked", s.MyLiked, []string{"l2", posted.ID}},
+		{"MyDisliked", s.MyDisliked, []string{"l1"}},
+		{"MySaved", s.MySaved, []string{}},
+		{"MyHidden", s.MyHidden, []string{}},
+	}
+	for _, l := range lists {
+		got, err := l.get(NewSubmissions, "")
+		if err != nil {
+			t.Errorf("%s: %v", l.name, err)
+			continue
+		}
+		if ids := submissionIDs(got); len(ids) != len(l.want) || (len(ids) > 0 && ids[0] != l.want[0]) {
+			t.Errorf("%s: got %v, want %v", l.name, ids, l.want)
+		}
+	}
+}
+
+func TestScriptLoginSessionClearRevokesToken(t *testing.T) {
+	var grants int32
+	s, _ := newTestScriptLoginSession(t, &grants)
+	old := s.Token().AccessToken
+
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	// The revoked token is rejected, and a new one requested.
+	if _, err := s.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&grants); n != 2 {
+		t.Errorf("got %d token requests, want 2", n)
+	}
+	if s.Token().AccessToken == old {
+		t.Error("token was not renewed after Clear")
+	}
+}
+
+func TestScriptLoginSessionListingScope(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	srv.SetScopes(IdentityScope, ReadScope)
+	s, err := NewScriptLoginSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := srv.Requests()
+	if _, err := s.MySubmitted(NewSubmissions, ""); err == nil {
+		t.Fatal("got a listing without the history scope")
+	} else if err, ok := err.(*MissingScopeError); !ok || err.Scope != HistoryScope {
+		t.Errorf("got %v from MySubmitted, want a *MissingScopeError for %q", err, HistoryScope)
+	}
+	if n := srv.Requests() - sent; n != 0 {
+		t.Errorf("sent %d requests without the history scope", n)
+	}
+}
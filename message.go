@@ -0,0 +1,125 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+)
+
+// Message represents a private message, or a comment reply or username
+// mention delivered to the inbox.
+type Message struct {
+	ID          string  `json:"id"`
+	FullID      string  `json:"name"`
+	Author      string  `json:"author"`
+	Dest        string  `json:"dest"`
+	Subject     string  `json:"subject"`
+	Body        string  `json:"body"`
+	BodyHTML    string  `json:"body_html"`
+	Context     string  `json:"context"`
+	ParentID    *string `json:"parent_id"`
+	DateCreated float64 `json:"created_utc"`
+	IsNew       bool    `json:"new"`
+	WasComment  bool    `json:"was_comment"`
+}
+
+// String returns the string representation of a message.
+func (m *Message) String() string {
+	return fmt.Sprintf("%s: %s", m.Author, m.Subject)
+}
+
+// SendMessage sends a private message to a user.
+func (s *OAuthSession) SendMessage(to, subject, text string) error {
+	return sendMessage(s, to, subject, text)
+}
+
+// Inbox returns the messages in the logged-in user's inbox, newest first.
+func (s *OAuthSession) Inbox(params ListingOptions) ([]*Message, error) {
+	return inbox(s, params)
+}
+
+// MarkMessagesRead marks messages of the inbox as read, given their full IDs.
+func (s *OAuthSession) MarkMessagesRead(ids ...string) error {
+	return markMessagesRead(s, ids)
+}
+
+// SendMessage sends a private message to a user.
+func (s LoginSession) SendMessage(to, subject, text string) error {
+	return sendMessage(s, to, subject, text)
+}
+
+// Inbox returns the messages in the logged-in user's inbox, newest first.
+func (s LoginSession) Inbox(params ListingOptions) ([]*Message, error) {
+	return inbox(s, params)
+}
+
+// MarkMessagesRead marks messages of the inbox as read, given their full IDs.
+func (s LoginSession) MarkMessagesRead(ids ...string) error {
+	return markMessagesRead(s, ids)
+}
+
+func sendMessage(c Client, to, subject, text string) error {
+	if err := requireScope(c, PrivateMessagesScope); err != nil {
+		return err
+	}
+	_, err := apiPost(c, &url.Values{
+		"to":      {to},
+		"subject": {subject},
+		"text":    {text},
+	}, "/api/compose")
+	return err
+}
+
+func inbox(c Client, params ListingOptions) ([]*Message, error) {
+	if err := requireScope(c, PrivateMessagesScope); err != nil {
+		return nil, err
+	}
+	v, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.Get(&v, "/message/inbox")
+	if err != nil {
+		return nil, err
+	}
+	return decodeMessages(body)
+}
+
+func markMessagesRead(c Client, ids []string) error {
+	if err := requireScope(c, PrivateMessagesScope); err != nil {
+		return err
+	}
+	_, err := c.Post(&url.Values{
+		"id": {strings.Join(ids, ",")},
+	}, "/api/read_message")
+	return err
+}
+
+// decodeMessages decodes a listing of messages.
+func decodeMessages(r io.Reader) ([]*Message, error) {
+	type Response struct {
+		Data struct {
+			Children []struct {
+				Data *Message
+			}
+		}
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(r).Decode(resp); err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*Message, len(resp.Data.Children))
+	for i, child := range resp.Data.Children {
+		msgs[i] = child.Data
+	}
+	return msgs, nil
+}
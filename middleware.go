@@ -0,0 +1,250 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Doer performs HTTP requests. *http.Client implements it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts an ordinary function to the Doer interface.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the Doer a session sends its requests through, e.g. to
+// add headers, tracing or metrics. Middlewares are applied to every request
+// of the session, cookie, OAuth and token requests alike.
+type Middleware func(next Doer) Doer
+
+// defaultClient is the Doer used by sessions that were not given one.
+var defaultClient Doer = &http.Client{
+	Timeout: time.Second * 30,
+}
+
+// chain wraps base with mw; the first middleware is the outermost one.
+func chain(base Doer, mw []Middleware) Doer {
+	if base == nil {
+		base = defaultClient
+	}
+	d := base
+	for i := len(mw) - 1; i >= 0; i-- {
+		d = mw[i](d)
+	}
+	return d
+}
+
+// appendMiddleware returns mw followed by more, never sharing mw's backing
+// array so session copies keep their own chains.
+func appendMiddleware(mw []Middleware, more []Middleware) []Middleware {
+	all := make([]Middleware, 0, len(mw)+len(more))
+	all = append(all, mw...)
+	return append(all, more...)
+}
+
+type retryKey struct{}
+
+// MarkRetry returns a copy of req marked as a new attempt of a request that
+// already failed, which IsRetry reports to middlewares. Sessions mark the
+// requests they re-send after renewing a rejected access token; retrying
+// middlewares should mark theirs too.
+func MarkRetry(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), retryKey{}, true))
+}
+
+// IsRetry reports whether req was marked by MarkRetry.
+func IsRetry(req *http.Request) bool {
+	retry, _ := req.Context().Value(retryKey{}).(bool)
+	return retry
+}
+
+// Use appends middlewares to the session's chain.
+func (s *Session) Use(mw ...Middleware) {
+	s.middleware = appendMiddleware(s.middleware, mw)
+}
+
+// SetHTTPClient replaces the Doer at the end of the session's middleware
+// chain, which defaults to an *http.Client with a 30 second timeout.
+func (s *Session) SetHTTPClient(c Doer) {
+	s.client = c
+}
+
+// doer returns the session's middleware chain, behind its cache if any.
+func (s Session) doer() Doer {
+	return s.cache.wrap(chain(s.client, s.middleware), "")
+}
+
+// doer returns the session's middleware chain, behind its cache if any,
+// caching responses under the logged-in user.
+func (s LoginSession) doer() Doer {
+	return s.cache.wrap(chain(s.client, s.middleware), "user:"+s.username)
+}
+
+// Use appends middlewares to the session's chain.
+func (s *OAuthSession) Use(mw ...Middleware) {
+	s.middleware = appendMiddleware(s.middleware, mw)
+}
+
+// SetHTTPClient replaces the Doer at the end of the session's middleware
+// chain, which defaults to an *http.Client with a 30 second timeout.
+func (s *OAuthSession) SetHTTPClient(c Doer) {
+	s.client = c
+}
+
+// doer returns the session's middleware chain, behind its cache if any.
+func (s *OAuthSession) doer() Doer {
+	return s.cache.wrap(chain(s.client, s.middleware), "user:"+s.username)
+}
+
+// OAuthOption configures an OAuthSession before it requests its first
+// access token.
+type OAuthOption func(*OAuthSession)
+
+// WithMiddleware makes the session use the given middlewares, including for
+// its first token request.
+func WithMiddleware(mw ...Middleware) OAuthOption {
+	return func(s *OAuthSession) {
+		s.Use(mw...)
+	}
+}
+
+// WithHTTPClient makes the session send its requests through c.
+func WithHTTPClient(c Doer) OAuthOption {
+	return func(s *OAuthSession) {
+		s.SetHTTPClient(c)
+	}
+}
+
+// EnforceUserAgent makes sure every request carries a descriptive
+// User-Agent, as reddit's API rules require. Requests without one, or with
+// Go's default, get fallback; if fallback is empty they fail instead.
+func EnforceUserAgent(fallback string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			ua := req.Header.Get("User-Agent")
+			if ua == "" || strings.HasPrefix(ua, "Go-http-client") {
+				if fallback == "" {
+					return nil, errors.New("request has no User-Agent")
+				}
+				req.Header.Set("User-Agent", fallback)
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// RequestID sets a random ID in the given header of every request that does
+// not have one yet, e.g. to correlate logs and traces. header defaults to
+// X-Request-Id.
+func RequestID(header string) Middleware {
+	if header == "" {
+		header = "X-Request-Id"
+	}
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				b := make([]byte, 16)
+				if _, err := rand.Read(b); err != nil {
+					return nil, err
+				}
+				req.Header.Set(header, hex.EncodeToString(b))
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// Timing calls observe after every request with its outcome and how long it
+// took to get the response headers.
+func Timing(observe func(req *http.Request, resp *http.Response, err error, d time.Duration)) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.Do(req)
+			observe(req, resp, err, time.Since(start))
+			return resp, err
+		})
+	}
+}
+
+// ResponseTooLargeError is returned when reading a response body that
+// exceeds the limit set with MaxResponseSize.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes", e.Limit)
+}
+
+// MaxResponseSize makes requests fail with a *ResponseTooLargeError when the
+// response body is larger than limit bytes. It is how sessions cap the
+// bodies they read, e.g. of huge comment threads:
+//
+//	s.Use(geddit.MaxResponseSize(8 << 20))
+//
+// or WithMiddleware(geddit.MaxResponseSize(8 << 20)) for OAuth sessions.
+// Responses are decoded as they are read, so the limit bounds how much of a
+// body is read rather than a buffer.
+func MaxResponseSize(limit int64) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.Do(req)
+			if err != nil {
+				return resp, err
+			}
+			if resp.ContentLength > limit {
+				resp.Body.Close()
+				return nil, &ResponseTooLargeError{Limit: limit}
+			}
+			resp.Body = &limitedBody{
+				ReadCloser: resp.Body,
+				remaining:  limit,
+				limit:      limit,
+			}
+			return resp, nil
+		})
+	}
+}
+
+// limitedBody is a response body that fails once more than limit bytes
+// have been read from it.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, &ResponseTooLargeError{Limit: b.limit}
+	}
+	// Read one byte past the limit to tell a body of exactly limit bytes
+	// from a larger one.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, &ResponseTooLargeError{Limit: b.limit}
+	}
+	return n, err
+}
@@ -0,0 +1,121 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// Approve approves a submission or comment, given its full ID, restoring
+// it if it was removed.
+func (s *OAuthSession) Approve(fullID string) error {
+	return approve(s, fullID)
+}
+
+// Remove removes a submission or comment, given its full ID. spam also
+// trains the subreddit's spam filter with it.
+func (s *OAuthSession) Remove(fullID string, spam bool) error {
+	return removeThing(s, fullID, spam)
+}
+
+// Approve approves a submission or comment, see OAuthSession.Approve.
+func (s LoginSession) Approve(fullID string) error {
+	return approve(s, fullID)
+}
+
+// Remove removes a submission or comment, see OAuthSession.Remove.
+func (s LoginSession) Remove(fullID string, spam bool) error {
+	return removeThing(s, fullID, spam)
+}
+
+func approve(c Client, fullID string) error {
+	if err := requireScope(c, ModPostsScope); err != nil {
+		return err
+	}
+	_, err := c.Post(&url.Values{"id": {fullID}}, "/api/approve")
+	return err
+}
+
+func removeThing(c Client, fullID string, spam bool) error {
+	if err := requireScope(c, ModPostsScope); err != nil {
+		return err
+	}
+	_, err := c.Post(&url.Values{
+		"id":   {fullID},
+		"spam": {strconv.FormatBool(spam)},
+	}, "/api/remove")
+	return err
+}
+
+// ModLogEntry is an entry of a subreddit's moderation log.
+type ModLogEntry struct {
+	ID             string  `json:"id"`
+	Action         string  `json:"action"`
+	Mod            string  `json:"mod"`
+	TargetFullname string  `json:"target_fullname"`
+	TargetAuthor   string  `json:"target_author"`
+	TargetTitle    string  `json:"target_title"`
+	TargetBody     string  `json:"target_body"`
+	Details        *string `json:"details"`
+	Description    *string `json:"description"`
+	Subreddit      string  `json:"subreddit"`
+	Created        float64 `json:"created_utc"`
+}
+
+// ModLogIterator iterates over a subreddit's moderation log. It is used
+// like RelationshipIterator.
+type ModLogIterator struct {
+	iter *listingIterator
+	cur  *ModLogEntry
+	err  error
+}
+
+// Next advances the iterator to the next entry, which will then be
+// available through Entry. It returns false when there are no more entries
+// or an error occurred.
+func (it *ModLogIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	t, ok := it.iter.next()
+	if !ok {
+		it.err = it.iter.err
+		return false
+	}
+	a := &ModLogEntry{}
+	if it.err = json.Unmarshal(t.Data, a); it.err != nil {
+		return false
+	}
+	it.cur = a
+	return true
+}
+
+// Entry returns the entry the iterator currently points to.
+func (it *ModLogIterator) Entry() *ModLogEntry {
+	return it.cur
+}
+
+// Err returns the first error encountered by the iterator.
+func (it *ModLogIterator) Err() error {
+	return it.err
+}
+
+// ModLog returns an iterator over the moderation log of a subreddit, newest
+// first, limited to actions of the given type, e.g. "removelink", if action
+// is not empty.
+func (s *OAuthSession) ModLog(subreddit, action string, params ListingOptions) *ModLogIterator {
+	if err := s.requireScope(ModLogScope); err != nil {
+		return &ModLogIterator{err: err}
+	}
+	var extra url.Values
+	if action != "" {
+		extra = url.Values{"type": {action}}
+	}
+	return &ModLogIterator{
+		iter: newListingIterator(s, params, extra, "/r/%s/about/log", subreddit),
+	}
+}
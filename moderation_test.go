@@ -0,0 +1,59 @@
+package geddit
+
+import (
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// modLogEntries returns the entries of it.
+func modLogEntries(t *testing.T, it *ModLogIterator) []*ModLogEntry {
+	var entries []*ModLogEntry
+	for it.Next() {
+		entries = append(entries, it.Entry())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestModLog(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, err := NewOAuthSession(geddittest.Moderator, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Remove("t3_l1", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Approve("t1_c2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Approve("t3_l1"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := modLogEntries(t, s.ModLog("golang", "", ListingOptions{Limit: 2}))
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	want := []struct{ action, target string }{
+		{"approvelink", "t3_l1"},
+		{"approvecomment", "t1_c2"},
+		{"removelink", "t3_l1"},
+	}
+	for i, e := range entries {
+		if e.Action != want[i].action || e.TargetFullname != want[i].target || e.Mod != geddittest.Moderator || e.ID == "" || e.Created == 0 {
+			t.Errorf("entry %d: got %+v, want %s of %s", i, e, want[i].action, want[i].target)
+		}
+	}
+
+	removals := modLogEntries(t, s.ModLog("golang", "removelink", ListingOptions{}))
+	if len(removals) != 1 || removals[0].Action != "removelink" {
+		t.Errorf("got removals %v", removals)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// conversationState represents the folders of the new modmail.
+type conversationState string
+
+const (
+	AllConversations          conversationState = "all"
+	NewConversations          conversationState = "new"
+	InProgressConversations   conversationState = "inprogress"
+	ArchivedConversations     conversationState = "archived"
+	ModConversations          conversationState = "mod"
+	NotificationConversations conversationState = "notifications"
+	HighlightedConversations  conversationState = "highlighted"
+)
+
+// ModmailOptions holds the parameters used to list modmail conversations.
+type ModmailOptions struct {
+	// After is the ID of the last conversation of the previous page.
+	After string `url:"after,omitempty"`
+	// Entity restricts the listing to the given subreddits.
+	Entity []string `url:"entity,omitempty,comma"`
+	Limit  int      `url:"limit,omitempty"`
+	// Sort is one of "recent", "mod", "reply" or "unread".
+	Sort string `url:"sort,omitempty"`
+}
+
+// ModmailParticipant is a user or subreddit taking part in a conversation.
+type ModmailParticipant struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	IsMod         bool   `json:"isMod"`
+	IsAdmin       bool   `json:"isAdmin"`
+	IsOP          bool   `json:"isOp"`
+	IsParticipant bool   `json:"isParticipant"`
+	IsHidden      bool   `json:"isHidden"`
+	IsDeleted     bool   `json:"isDeleted"`
+}
+
+// ModmailMessage is a single message of a modmail conversation.
+type ModmailMessage struct {
+	ID           string             `json:"id"`
+	Body         string             `json:"body"`
+	BodyMarkdown string             `json:"bodyMarkdown"`
+	Author       ModmailParticipant `json:"author"`
+	IsInternal   bool               `json:"isInternal"`
+	Date         time.Time          `json:"date"`
+}
+
+// String returns the string representation of a modmail message.
+func (m *ModmailMessage) String() string {
+	return fmt.Sprintf("%s: %s", m.Author.Name, m.BodyMarkdown)
+}
+
+// ModAction is a moderator action (archive, highlight, mute, ...) recorded
+// in a modmail conversation.
+type ModAction struct {
+	ID           string             `json:"id"`
+	ActionTypeID int                `json:"actionTypeId"`
+	Author       ModmailParticipant `json:"author"`
+	Date         time.Time          `json:"date"`
+}
+
+// Conversation represents a new modmail conversation. Messages and
+// ModActions are only populated as far as reddit returned them: listings
+// only carry the most recent message, Conversation returns all of them.
+type Conversation struct {
+	ID             string    `json:"id"`
+	Subject        string    `json:"subject"`
+	State          int       `json:"state"`
+	IsAuto         bool      `json:"isAuto"`
+	IsHighlighted  bool      `json:"isHighlighted"`
+	IsInternal     bool      `json:"isInternal"`
+	IsRepliable    bool      `json:"isRepliable"`
+	NumMessages    int       `json:"numMessages"`
+	LastUpdated    time.Time `json:"lastUpdated"`
+	LastUserUpdate time.Time `json:"lastUserUpdate"`
+	LastModUpdate  time.Time `json:"lastModUpdate"`
+	LastUnread     time.Time `json:"lastUnread"`
+	Owner          struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"displayName"`
+		Type        string `json:"type"`
+	} `json:"owner"`
+	Authors     []ModmailParticipant `json:"authors"`
+	Participant ModmailParticipant   `json:"participant"`
+	Messages    []*ModmailMessage    `json:"-"`
+	ModActions  []*ModAction         `json:"-"`
+
+	objIDs []struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+}
+
+// UnmarshalJSON decodes a conversation, keeping track of the order of its
+// messages and mod actions so attach can fill them in later.
+func (c *Conversation) UnmarshalJSON(b []byte) error {
+	type conversation Conversation
+	aux := struct {
+		*conversation
+		ObjIDs []struct {
+			ID  string `json:"id"`
+			Key string `json:"key"`
+		} `json:"objIds"`
+	}{conversation: (*conversation)(c)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	c.objIDs = aux.ObjIDs
+	return nil
+}
+
+// String returns the string representation of a conversation.
+func (c *Conversation) String() string {
+	return fmt.Sprintf("%s: %s (%d messages)", c.Owner.DisplayName, c.Subject, c.NumMessages)
+}
+
+// attach fills in Messages and ModActions, in conversation order, from the
+// lookup tables reddit returns alongside conversations.
+func (c *Conversation) attach(messages map[string]*ModmailMessage, actions map[string]*ModAction) {
+	c.Messages, c.ModActions = nil, nil
+	for _, obj := range c.objIDs {
+		switch obj.Key {
+		case "messages":
+			if m, ok := messages[obj.ID]; ok {
+				c.Messages = append(c.Messages, m)
+			}
+		case "modActions":
+			if a, ok := actions[obj.ID]; ok {
+				c.ModActions = append(c.ModActions, a)
+			}
+		}
+	}
+}
+
+// conversationResponse is returned by the endpoints acting on a single
+// conversation.
+type conversationResponse struct {
+	Conversation *Conversation              `json:"conversation"`
+	Messages     map[string]*ModmailMessage `json:"messages"`
+	ModActions   map[string]*ModAction      `json:"modActions"`
+}
+
+// decodeConversation decodes a conversationResponse and returns its
+// conversation with messages and mod actions attached.
+func decodeConversation(body *json.Decoder) (*Conversation, error) {
+	r := &conversationResponse{}
+	if err := body.Decode(r); err != nil {
+		return nil, err
+	}
+	if r.Conversation == nil {
+		return nil, errors.New("modmail: response has no conversation")
+	}
+	r.Conversation.attach(r.Messages, r.ModActions)
+	return r.Conversation, nil
+}
+
+// Conversations returns the modmail conversations in the given state, most
+// recent first. Use the ID of the last conversation as ModmailOptions.After
+// to fetch the next page.
+func (s *OAuthSession) Conversations(state conversationState, params ModmailOptions) ([]*Conversation, error) {
+	if err := s.requireScope(ModMailScope); err != nil {
+		return nil, err
+	}
+	v, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+	if state != "" {
+		v.Set("state", string(state))
+	}
+
+	body, err := s.Get(&v, "/api/mod/conversations")
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Conversations   map[string]*Conversation   `json:"conversations"`
+		ConversationIDs []string                   `json:"conversationIds"`
+		Messages        map[string]*ModmailMessage `json:"messages"`
+	}
+	r := &Response{}
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+
+	conversations := make([]*Conversation, 0, len(r.ConversationIDs))
+	for _, id := range r.ConversationIDs {
+		c, ok := r.Conversations[id]
+		if !ok {
+			continue
+		}
+		c.attach(r.Messages, nil)
+		conversations = append(conversations, c)
+	}
+	return conversations, nil
+}
+
+// Conversation returns a modmail conversation with all of its messages and
+// mod actions, optionally marking it as read.
+func (s *OAuthSession) Conversation(id string, markRead bool) (*Conversation, error) {
+	if err := s.requireScope(ModMailScope); err != nil {
+		return nil, err
+	}
+	v := &url.Values{
+		"markRead": {strconv.FormatBool(markRead)},
+	}
+	body, err := s.Get(v, "/api/mod/conversations/%s", id)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConversation(json.NewDecoder(body))
+}
+
+// ReplyConversation adds a message to a modmail conversation. Internal
+// messages are only visible to moderators; hiding the author sends the
+// message as the subreddit.
+func (s *OAuthSession) ReplyConversation(id, text string, isInternal, isAuthorHidden bool) (*Conversation, error) {
+	if err := s.requireScope(ModMailScope); err != nil {
+		return nil, err
+	}
+	v := &url.Values{
+		"body":           {text},
+		"isInternal":     {strconv.FormatBool(isInternal)},
+		"isAuthorHidden": {strconv.FormatBool(isAuthorHidden)},
+	}
+	body, err := s.Post(v, "/api/mod/conversations/%s", id)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConversation(json.NewDecoder(body))
+}
+
+// conversationAction posts to one of the /api/mod/conversations/{id}/{action}
+// endpoints.
+func (s *OAuthSession) conversationAction(id, action string, v *url.Values) error {
+	if err := s.requireScope(ModMailScope); err != nil {
+		return err
+	}
+	_, err := s.Post(v, "/api/mod/conversations/%s/%s", id, action)
+	return err
+}
+
+// ArchiveConversation archives a modmail conversation.
+func (s *OAuthSession) ArchiveConversation(id string) error {
+	return s.conversationAction(id, "archive", nil)
+}
+
+// UnarchiveConversation moves an archived modmail conversation back to
+// the inbox.
+func (s *OAuthSession) UnarchiveConversation(id string) error {
+	return s.conversationAction(id, "unarchive", nil)
+}
+
+// HighlightConversation marks a modmail conversation as highlighted.
+func (s *OAuthSession) HighlightConversation(id string) error {
+	return s.conversationAction(id, "highlight", nil)
+}
+
+// MuteConversation mutes the non-moderator participant of a modmail
+// conversation for the given number of hours (reddit accepts 72, 168 or 672).
+func (s *OAuthSession) MuteConversation(id string, hours int) error {
+	return s.conversationAction(id, "mute", &url.Values{
+		"num_hours": {strconv.Itoa(hours)},
+	})
+}
+
+// UnmuteConversation unmutes the non-moderator participant of a modmail
+// conversation.
+func (s *OAuthSession) UnmuteConversation(id string) error {
+	return s.conversationAction(id, "unmute", nil)
+}
+
+// MarkConversationsRead marks the given modmail conversations as read.
+func (s *OAuthSession) MarkConversationsRead(ids ...string) error {
+	if err := s.requireScope(ModMailScope); err != nil {
+		return err
+	}
+	_, err := s.Post(&url.Values{
+		"conversationIds": {strings.Join(ids, ",")},
+	}, "/api/mod/conversations/read")
+	return err
+}
+
+// MarkConversationsUnread marks the given modmail conversations as unread.
+func (s *OAuthSession) MarkConversationsUnread(ids ...string) error {
+	if err := s.requireScope(ModMailScope); err != nil {
+		return err
+	}
+	_, err := s.Post(&url.Values{
+		"conversationIds": {strings.Join(ids, ",")},
+	}, "/api/mod/conversations/unread")
+	return err
+}
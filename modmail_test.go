@@ -0,0 +1,85 @@
+package geddit
+
+import (
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestConversation(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, err := NewOAuthSession(geddittest.Moderator, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := srv.AddConversation("golang", geddittest.Username, "Removed post", "Why was l1 removed?")
+
+	if _, err := s.ReplyConversation(id, "It was not, see the thread.", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.HighlightConversation(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReplyConversation(id, "Keep an eye on this one.", true, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ArchiveConversation(id); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := s.Conversation(id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != id || c.Subject != "Removed post" || c.NumMessages != 3 || !c.IsHighlighted || c.Owner.DisplayName != "golang" {
+		t.Errorf("got conversation %+v", c)
+	}
+	if c.Participant.Name != geddittest.Username || !c.Participant.IsOP || len(c.Authors) != 2 {
+		t.Errorf("got participant %+v and authors %+v", c.Participant, c.Authors)
+	}
+
+	// Messages and mod actions are joined in conversation order.
+	bodies := []string{"Why was l1 removed?", "It was not, see the thread.", "Keep an eye on this one."}
+	if len(c.Messages) != len(bodies) {
+		t.Fatalf("got %d messages, want %d", len(c.Messages), len(bodies))
+	}
+	for i, m := range c.Messages {
+		if m.BodyMarkdown != bodies[i] || m.Date.IsZero() {
+			t.Errorf("message %d: got %+v, want %q", i, m, bodies[i])
+		}
+	}
+	if m := c.Messages[2]; !m.IsInternal || m.Author.Name != geddittest.Moderator || !m.Author.IsMod {
+		t.Errorf("got internal message %+v", m)
+	}
+	if len(c.ModActions) != 2 || c.ModActions[0].ActionTypeID != 0 || c.ModActions[1].ActionTypeID != 2 {
+		t.Fatalf("got mod actions %+v, want a highlight then an archive", c.ModActions)
+	}
+	if a := c.ModActions[0]; a.Author.Name != geddittest.Moderator || a.ID == "" {
+		t.Errorf("got mod action %+v", a)
+	}
+
+	// Listings only carry the last message of each conversation.
+	other := srv.AddConversation("golang", geddittest.Username, "Flair", "Can I get flair?")
+	list, err := s.Conversations(AllConversations, ModmailOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != other {
+		t.Fatalf("got conversations %v, want the unarchived one", list)
+	}
+	if m := list[0].Messages; len(m) != 1 || m[0].BodyMarkdown != "Can I get flair?" {
+		t.Errorf("got listed messages %+v", m)
+	}
+	archived, err := s.Conversations(ArchivedConversations, ModmailOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 || archived[0].ID != id {
+		t.Fatalf("got archived conversations %v", archived)
+	}
+	if m := archived[0].Messages; len(m) != 1 || m[0].BodyMarkdown != bodies[2] || len(archived[0].ModActions) != 0 {
+		t.Errorf("got listed messages %+v and mod actions %+v", m, archived[0].ModActions)
+	}
+}
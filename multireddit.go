@@ -0,0 +1,276 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+)
+
+// Multi represents a multireddit (custom feed).
+type Multi struct {
+	Name            string   `json:"name"`
+	DisplayName     string   `json:"display_name"`
+	Path            string   `json:"path"`
+	Owner           string   `json:"owner"`
+	Desc            string   `json:"description_md"`
+	DescHTML        string   `json:"description_html"`
+	Visibility      string   `json:"visibility"`
+	WeightingScheme string   `json:"weighting_scheme"`
+	KeyColor        string   `json:"key_color"`
+	IconURL         string   `json:"icon_url"`
+	CopiedFrom      *string  `json:"copied_from"`
+	DateCreated     float64  `json:"created_utc"`
+	NumSubs         int      `json:"num_subscribers"`
+	CanEdit         bool     `json:"can_edit"`
+	IsNSFW          bool     `json:"over_18"`
+	Subreddits      []string `json:"-"`
+}
+
+// UnmarshalJSON decodes a multireddit, flattening its subreddit list.
+func (m *Multi) UnmarshalJSON(b []byte) error {
+	type multi Multi
+	aux := struct {
+		*multi
+		Subreddits []struct {
+			Name string `json:"name"`
+		} `json:"subreddits"`
+	}{multi: (*multi)(m)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	m.Subreddits = make([]string, len(aux.Subreddits))
+	for i, sr := range aux.Subreddits {
+		m.Subreddits[i] = sr.Name
+	}
+	return nil
+}
+
+// String returns the string representation of a multireddit.
+func (m *Multi) String() string {
+	return fmt.Sprintf("%s (%d subreddits)", m.Path, len(m.Subreddits))
+}
+
+// model returns the JSON description of the multireddit reddit expects when
+// creating or updating it.
+func (m *Multi) model() (string, error) {
+	type sr struct {
+		Name string `json:"name"`
+	}
+	model := struct {
+		DisplayName     string `json:"display_name,omitempty"`
+		Desc            string `json:"description_md,omitempty"`
+		Visibility      string `json:"visibility,omitempty"`
+		WeightingScheme string `json:"weighting_scheme,omitempty"`
+		KeyColor        string `json:"key_color,omitempty"`
+		Subreddits      []sr   `json:"subreddits"`
+	}{
+		DisplayName:     m.DisplayName,
+		Desc:            m.Desc,
+		Visibility:      m.Visibility,
+		WeightingScheme: m.WeightingScheme,
+		KeyColor:        m.KeyColor,
+		Subreddits:      make([]sr, len(m.Subreddits)),
+	}
+	for i, name := range m.Subreddits {
+		model.Subreddits[i].Name = name
+	}
+	b, err := json.Marshal(model)
+	return string(b), err
+}
+
+// MultiPath returns the path of the multireddit of the given user and name,
+// as accepted by the multireddit methods.
+func MultiPath(username, name string) string {
+	return fmt.Sprintf("/user/%s/m/%s", username, name)
+}
+
+// multiPath normalizes a multireddit path so it can follow "/api/multi".
+func multiPath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
+
+// decodeMulti decodes a single LabeledMulti thing.
+func decodeMulti(body *json.Decoder) (*Multi, error) {
+	type Response struct {
+		Data Multi `json:"data"`
+	}
+	r := &Response{}
+	if err := body.Decode(r); err != nil {
+		return nil, err
+	}
+	return &r.Data, nil
+}
+
+// multis fetches a list of LabeledMulti things.
+func (s *OAuthSession) multis(urlformat string, urlvars ...interface{}) ([]*Multi, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, urlformat, urlvars...)
+	if err != nil {
+		return nil, err
+	}
+
+	var r []struct {
+		Data *Multi `json:"data"`
+	}
+	if err = json.NewDecoder(body).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	multis := make([]*Multi, len(r))
+	for i, child := range r {
+		multis[i] = child.Data
+	}
+	return multis, nil
+}
+
+// MyMultis returns the multireddits of the logged-in user.
+func (s *OAuthSession) MyMultis() ([]*Multi, error) {
+	return s.multis("/api/multi/mine")
+}
+
+// UserMultis returns the public multireddits of a user.
+func (s *OAuthSession) UserMultis(username string) ([]*Multi, error) {
+	return s.multis("/api/multi/user/%s", username)
+}
+
+// Multi returns the multireddit at the given path, e.g. "/user/foo/m/bar".
+func (s *OAuthSession) Multi(path string) (*Multi, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/api/multi%s", multiPath(path))
+	if err != nil {
+		return nil, err
+	}
+	return decodeMulti(json.NewDecoder(body))
+}
+
+// SaveMulti creates the multireddit at the given path, or replaces its
+// description, visibility and subreddits if it already exists.
+func (s *OAuthSession) SaveMulti(path string, m *Multi) (*Multi, error) {
+	if err := s.requireScope(SubscribeScope); err != nil {
+		return nil, err
+	}
+	model, err := m.model()
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.do(PUT, &url.Values{
+		"model": {model},
+	}, "/api/multi%s", multiPath(path))
+	if err != nil {
+		return nil, err
+	}
+	return decodeMulti(json.NewDecoder(body))
+}
+
+// DeleteMulti deletes the multireddit at the given path.
+func (s *OAuthSession) DeleteMulti(path string) error {
+	if err := s.requireScope(SubscribeScope); err != nil {
+		return err
+	}
+	_, err := s.do(DELETE, nil, "/api/multi%s", multiPath(path))
+	return err
+}
+
+// CopyMulti copies a multireddit into one owned by the logged-in user, named
+// after displayName.
+func (s *OAuthSession) CopyMulti(from, displayName string) (*Multi, error) {
+	if err := s.requireScope(SubscribeScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Post(&url.Values{
+		"from":         {multiPath(from)},
+		"display_name": {displayName},
+	}, "/api/multi/copy")
+	if err != nil {
+		return nil, err
+	}
+	return decodeMulti(json.NewDecoder(body))
+}
+
+// RenameMulti changes the display name of a multireddit, which also moves it
+// to a new path. The renamed multireddit is returned.
+func (s *OAuthSession) RenameMulti(from, displayName string) (*Multi, error) {
+	if err := s.requireScope(SubscribeScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Post(&url.Values{
+		"from":         {multiPath(from)},
+		"display_name": {displayName},
+	}, "/api/multi/rename")
+	if err != nil {
+		return nil, err
+	}
+	return decodeMulti(json.NewDecoder(body))
+}
+
+// AddMultiSubreddit adds a subreddit to a multireddit.
+func (s *OAuthSession) AddMultiSubreddit(path, subreddit string) error {
+	if err := s.requireScope(SubscribeScope); err != nil {
+		return err
+	}
+	model, err := json.Marshal(map[string]string{"name": subreddit})
+	if err != nil {
+		return err
+	}
+	_, err = s.do(PUT, &url.Values{
+		"model": {string(model)},
+	}, "/api/multi%s/r/%s", multiPath(path), subreddit)
+	return err
+}
+
+// RemoveMultiSubreddit removes a subreddit from a multireddit.
+func (s *OAuthSession) RemoveMultiSubreddit(path, subreddit string) error {
+	if err := s.requireScope(SubscribeScope); err != nil {
+		return err
+	}
+	_, err := s.do(DELETE, nil, "/api/multi%s/r/%s", multiPath(path), subreddit)
+	return err
+}
+
+// MultiSubmissions returns the submissions of the subreddits of a
+// multireddit, like SubredditSubmissions does for a single subreddit.
+func (s *OAuthSession) MultiSubmissions(path string, sort popularitySort, params ListingOptions) ([]*Submission, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	v, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.Get(&v, "%s/%s", multiPath(path), sort)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data struct {
+			Children []struct {
+				Data *Submission
+			}
+		}
+	}
+	r := new(Response)
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+
+	submissions := make([]*Submission, len(r.Data.Children))
+	web := s.Endpoints().WWW
+	for i, child := range r.Data.Children {
+		child.Data.web = web
+		submissions[i] = child.Data
+	}
+	return submissions, nil
+}
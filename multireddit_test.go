@@ -0,0 +1,99 @@
+package geddit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestMultiRoundTrip(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := MultiPath(geddittest.Username, "gophers")
+	saved, err := s.SaveMulti(path, &Multi{
+		DisplayName: "Gophers",
+		Desc:        "All things Go.",
+		Visibility:  "public",
+		KeyColor:    "#00add8",
+		Subreddits:  []string{"golang"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Path != path || saved.Owner != geddittest.Username || !saved.CanEdit || saved.CopiedFrom != nil {
+		t.Errorf("got saved multi %+v", saved)
+	}
+	if err := s.AddMultiSubreddit(path, "rust"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := s.Multi(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.DisplayName != "Gophers" || m.Desc != "All things Go." || m.Visibility != "public" || m.KeyColor != "#00add8" {
+		t.Errorf("got multi %+v", m)
+	}
+	if want := []string{"golang", "rust"}; !reflect.DeepEqual(m.Subreddits, want) {
+		t.Errorf("got subreddits %q, want %q", m.Subreddits, want)
+	}
+	if err := s.RemoveMultiSubreddit(path, "rust"); err != nil {
+		t.Fatal(err)
+	}
+
+	submissions, err := s.MultiSubmissions(path, NewSubmissions, ListingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(submissions) != 2 || submissions[0].ID != "l2" || submissions[1].ID != "l1" {
+		t.Errorf("got submissions %v", submissions)
+	}
+
+	copied, err := s.CopyMulti(path, "Gopher copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Path != MultiPath(geddittest.Username, "gopher_copy") || copied.CopiedFrom == nil || *copied.CopiedFrom != path {
+		t.Errorf("got copied multi %+v", copied)
+	}
+	if !reflect.DeepEqual(copied.Subreddits, []string{"golang"}) {
+		t.Errorf("got copied subreddits %q", copied.Subreddits)
+	}
+	renamed, err := s.RenameMulti(copied.Path, "Renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Path != MultiPath(geddittest.Username, "renamed") {
+		t.Errorf("got renamed multi %+v", renamed)
+	}
+
+	mine, err := s.MyMultis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, m := range mine {
+		paths = append(paths, m.Path)
+	}
+	if want := []string{path, renamed.Path}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got multis %q, want %q", paths, want)
+	}
+
+	if err := s.DeleteMulti(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Multi(path); err == nil {
+		t.Error("got deleted multi")
+	}
+	// Only public multireddits are listed for other users.
+	if others, err := s.UserMultis(geddittest.Moderator); err != nil || len(others) != 0 {
+		t.Errorf("got %v, %v", others, err)
+	}
+}
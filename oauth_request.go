@@ -2,10 +2,8 @@ package geddit
 
 import (
 	"bytes"
-	"errors"
-	"fmt"
+	"encoding/json"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"time"
@@ -18,6 +16,8 @@ const (
 	GET            method = "GET"
 	POST           method = "POST"
 	PATCH          method = "PATCH"
+	PUT            method = "PUT"
+	DELETE         method = "DELETE"
 )
 
 type oauthRequest struct {
@@ -25,14 +25,59 @@ type oauthRequest struct {
 	url         string
 	useragent   string
 	values      *url.Values
+	jsonBody    []byte
+	retry       bool
 	action      method
+	log         *requestLogger
+	doer        Doer
+	flights     *flightGroup
 }
 
-func ourl(format string, args ...interface{}) string {
-	return fmt.Sprintf(OAUTH_BASE_URL+format, args...)
+func (r oauthRequest) getResponse() (*bytes.Buffer, error) {
+	req, err := r.newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	// Concurrent identical GETs share one request when coalescing is on.
+	if r.action == GET {
+		return r.flights.do(req.URL.String(), func() ([]byte, error) {
+			return r.fetch(req)
+		})
+	}
+	body, err := r.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(body), nil
 }
 
-func (r oauthRequest) getResponse() (*bytes.Buffer, error) {
+// decode sends the request and decodes the JSON response into v as it is
+// read, see request.decode.
+func (r oauthRequest) decode(v interface{}) error {
+	req, err := r.newRequest()
+	if err != nil {
+		return err
+	}
+	if (r.flights != nil && r.action == GET) || r.log.dumpsBodies() {
+		body, err := r.getResponse()
+		if err != nil {
+			return err
+		}
+		return json.NewDecoder(body).Decode(v)
+	}
+
+	resp, err := r.send(req)
+	if err != nil {
+		return err
+	}
+	return decodeBody(resp, v)
+}
+
+// newRequest builds the HTTP request. Values go in the query string of
+// GETs and in the form body of other methods, unless a JSON body is set;
+// GETs never have a body.
+func (r oauthRequest) newRequest() (*http.Request, error) {
 	// Determine the HTTP action.
 	var buffer bytes.Buffer
 	var action, finalurl string
@@ -42,22 +87,16 @@ func (r oauthRequest) getResponse() (*bytes.Buffer, error) {
 		if r.values != nil {
 			finalurl = r.url + "?" + r.values.Encode()
 		}
-	} else if r.action == POST {
-		action = "POST"
-		finalurl = r.url
-		if r.values != nil {
-			buffer.WriteString(r.values.Encode())
-		}
 	} else {
-		action = "PATCH"
+		action = string(r.action)
 		finalurl = r.url
-		if r.values != nil {
+		if r.jsonBody != nil {
+			buffer.Write(r.jsonBody)
+		} else if r.values != nil {
 			buffer.WriteString(r.values.Encode())
 		}
 	}
 
-	log.Println("finalurl", finalurl)
-
 	// Create a request and add the proper headers.
 	req, err := http.NewRequest(action, finalurl, &buffer)
 	if err != nil {
@@ -65,25 +104,48 @@ func (r oauthRequest) getResponse() (*bytes.Buffer, error) {
 	}
 	req.Header.Set("User-Agent", r.useragent)
 	req.Header.Set("Authorization", "bearer "+r.accessToken)
+	if r.retry {
+		req = MarkRetry(req)
+	}
+	if r.jsonBody != nil && r.action != GET {
+		req.Header.Set("Content-Type", "application/json")
+	} else if buffer.Len() > 0 {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return req, nil
+}
 
-	cl := &http.Client{
-		Timeout: time.Second * 30,
+// send sends req and checks the response status. The caller must close the
+// response body.
+func (r oauthRequest) send(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := r.doer.Do(req)
+	if err != nil {
+		r.log.logError(req.Method, req.URL.String(), err, start)
+		return nil, err
+	}
+	r.log.logResponse(req.Method, req.URL.String(), resp, start)
+	// PUT answers 201 Created and DELETE may answer 204 No Content.
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, newStatusError(resp)
 	}
+	return resp, nil
+}
 
-	// Handle the request
-	resp, err := cl.Do(req)
+// fetch sends req and returns the response body.
+func (r oauthRequest) fetch(req *http.Request) ([]byte, error) {
+	resp, err := r.send(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(resp.Status)
-	}
 
 	respbytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	r.log.logBody(req.URL.String(), respbytes)
 
-	return bytes.NewBuffer(respbytes), nil
+	return respbytes, nil
 }
@@ -10,29 +10,62 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-querystring/query"
 )
 
 // OAuthSession represents an OAuth session with reddit.com --
 // all authenticated API calls are methods bound to this type.
+//
+// An OAuthSession is safe for concurrent use. When its access token
+// expires, or reddit rejects it, one request renews it while the others
+// wait for the new token. The configuration methods, such as Use,
+// SetHTTPClient, SetCache, SetCoalescing and SetLogger, must however be
+// called before the session is shared.
 type OAuthSession struct {
 	username     string
 	password     string
 	clientID     string
 	clientSecret string
+	useragent    string
+
+	// tokenMu guards the token fields and is held while renewing them.
+	tokenMu      sync.Mutex
 	accessToken  string
 	tokenType    string
+	refreshToken string
 	expiresIn    int
+	expiry       time.Time
 	scope        string
-	useragent    string
+
+	store    TokenStore
+	storeKey string
+
+	log        *requestLogger
+	client     Doer
+	middleware []Middleware
+	cache      *responseCache
+	flights    *flightGroup
+	endpoints  Endpoints
+	// pool is the Pool whose tracking middleware is installed, if any.
+	pool *Pool
+
+	scopeMu    sync.Mutex
+	scopes     map[string]bool
+	usedScopes map[string]bool
 }
 
 // NewLoginSession creates a new session for those who want to log into a
 // reddit account via OAuth.
-func NewOAuthSession(username, password, useragent, clientID, clientSecret string) (*OAuthSession, error) {
+// Options are applied before the first access token is requested. With
+// WithTokenStore, a stored token is used instead if it has not expired.
+func NewOAuthSession(username, password, useragent, clientID, clientSecret string, opts ...OAuthOption) (*OAuthSession, error) {
 	session := &OAuthSession{
 		username:     username,
 		password:     password,
@@ -40,38 +73,139 @@ func NewOAuthSession(username, password, useragent, clientID, clientSecret strin
 		clientSecret: clientSecret,
 		useragent:    useragent,
 	}
+	for _, opt := range opts {
+		opt(session)
+	}
 
-	err := session.newToken(&url.Values{
-		"username":   {username},
-		"password":   {password},
-		"grant_type": {"password"},
-	})
-	if err != nil {
+	if session.store != nil {
+		t, err := session.store.Load(session.storeKey)
+		if err == nil {
+			session.setToken(t)
+		} else if err != ErrNoToken {
+			return nil, err
+		}
+	}
+	if _, err := session.token(); err != nil {
 		return nil, err
 	}
 	return session, nil
 }
 
+// WithTokenStore makes the session save its tokens in store under key, or
+// under the username if key is empty, and resume from the token saved
+// there instead of logging in again.
+func WithTokenStore(store TokenStore, key string) OAuthOption {
+	return func(s *OAuthSession) {
+		if key == "" {
+			key = s.username
+		}
+		s.store = store
+		s.storeKey = key
+	}
+}
+
+// Token returns the session's current token.
+func (s *OAuthSession) Token() *Token {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+	return s.tokenLocked()
+}
+
+func (s *OAuthSession) tokenLocked() *Token {
+	return &Token{
+		AccessToken:  s.accessToken,
+		TokenType:    s.tokenType,
+		RefreshToken: s.refreshToken,
+		Scope:        s.scope,
+		Expiry:       s.expiry,
+	}
+}
+
+// setToken sets the token fields. s.tokenMu must be held once the session
+// is shared.
+func (s *OAuthSession) setToken(t *Token) {
+	s.accessToken = t.AccessToken
+	s.tokenType = t.TokenType
+	s.refreshToken = t.RefreshToken
+	s.expiry = t.Expiry
+	s.scope = t.Scope
+	s.scopeMu.Lock()
+	s.scopes = parseScopes(t.Scope)
+	s.scopeMu.Unlock()
+}
+
+// token returns the session's access token, renewing it first if needed.
+func (s *OAuthSession) token() (string, error) {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+	if err := s.renewLocked(); err != nil {
+		return "", err
+	}
+	return s.accessToken, nil
+}
+
+// tokenRejected renews the access token after reddit rejected it. Unless
+// another request already replaced it, in which case the new one is
+// returned right away.
+func (s *OAuthSession) tokenRejected(rejected string) (string, error) {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+	if s.accessToken == rejected {
+		s.accessToken = ""
+	}
+	if err := s.renewLocked(); err != nil {
+		return "", err
+	}
+	return s.accessToken, nil
+}
+
+// renewLocked requests a new access token if the session has none or it is
+// about to expire, with the refresh token if there is one and the password
+// otherwise. s.tokenMu must be held.
+func (s *OAuthSession) renewLocked() error {
+	if s.accessToken != "" && !s.tokenLocked().Expired() {
+		return nil
+	}
+	if s.refreshToken != "" {
+		err := s.newToken(&url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {s.refreshToken},
+		})
+		if err == nil || s.password == "" {
+			return err
+		}
+	}
+	return s.newToken(&url.Values{
+		"username":   {s.username},
+		"password":   {s.password},
+		"grant_type": {"password"},
+	})
+}
+
+// newToken requests an access token with the given grant. s.tokenMu must be
+// held.
 func (s *OAuthSession) newToken(postValues *url.Values) error {
 
-	loginURL := "https://www.reddit.com/api/v1/access_token"
+	loginURL := s.endpoints.tokenURL("/api/v1/access_token")
 	req, err := http.NewRequest("POST", loginURL, strings.NewReader(postValues.Encode()))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", s.useragent)
 
 	// Set the auth header
 	req.SetBasicAuth(s.clientID, s.clientSecret)
 
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
+	start := time.Now()
+	resp, err := s.doer().Do(req)
 	if err != nil {
+		s.log.logError("POST", loginURL, err, start)
 		return err
 	}
 	defer resp.Body.Close()
+	s.log.logResponse("POST", loginURL, resp, start)
 
 	if resp.StatusCode != http.StatusOK {
 		return errors.New(resp.Status)
@@ -83,10 +217,12 @@ func (s *OAuthSession) newToken(postValues *url.Values) error {
 	}
 
 	type Response struct {
-		AccessToken string `json:"access_token"`
-		TokenType   string `json:"token_type"`
-		ExpiresIn   int    `json:"expires_in"`
-		Scope       string `json:"scope"`
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+		Error        string `json:"error"`
 	}
 
 	r := &Response{}
@@ -94,19 +230,40 @@ func (s *OAuthSession) newToken(postValues *url.Values) error {
 	if err != nil {
 		return err
 	}
+	// Failed grants are reported with a 200.
+	if r.Error != "" {
+		return errors.New(r.Error)
+	}
 
-	s.accessToken = r.AccessToken
-	s.tokenType = r.TokenType
+	t := &Token{
+		AccessToken:  r.AccessToken,
+		TokenType:    r.TokenType,
+		RefreshToken: r.RefreshToken,
+		Scope:        r.Scope,
+	}
+	if t.RefreshToken == "" {
+		// Refreshing does not always return a new refresh token.
+		t.RefreshToken = s.refreshToken
+	}
+	if r.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
 	s.expiresIn = r.ExpiresIn
-	s.scope = r.Scope
-
-	log.Println(s.scope)
+	s.setToken(t)
 
+	if s.store != nil {
+		return s.store.Save(s.storeKey, t)
+	}
 	return nil
 }
 
-func (s OAuthSession) RevokeToken() error {
-	revokeURL := "https://www.reddit.com/api/v1/revoke_token"
+// RevokeToken revokes the session's access token, and deletes it from the
+// session's TokenStore if any.
+func (s *OAuthSession) RevokeToken() error {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+
+	revokeURL := s.endpoints.tokenURL("/api/v1/revoke_token")
 	postValues := &url.Values{
 		"token":           {s.accessToken},
 		"token_type_hint": {s.tokenType},
@@ -117,15 +274,14 @@ func (s OAuthSession) RevokeToken() error {
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", s.useragent)
 	req.SetBasicAuth(s.clientID, s.clientSecret)
 
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
-	defer resp.Body.Close()
+	resp, err := s.doer().Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	// 401 returned if basic auth failed
 	// 204 is returned even if given token is invalid
@@ -133,84 +289,282 @@ func (s OAuthSession) RevokeToken() error {
 		return errors.New(resp.Status)
 	}
 
+	if s.store != nil {
+		return s.store.Delete(s.storeKey)
+	}
 	return nil
 }
 
 func (s *OAuthSession) Get(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
-	surl := ourl(urlformat, urlvars...)
-	req := &oauthRequest{
-		accessToken: s.accessToken,
-		url:         surl,
-		useragent:   s.useragent,
-		action:      GET,
-		values:      params,
-	}
-	return req.getResponse()
+	return s.do(GET, params, urlformat, urlvars...)
 }
 
 func (s *OAuthSession) Post(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
-	surl := ourl(urlformat, urlvars...)
-	req := &oauthRequest{
-		accessToken: s.accessToken,
-		url:         surl,
-		useragent:   s.useragent,
-		action:      POST,
-		values:      params,
-	}
-	return req.getResponse()
+	return s.do(POST, params, urlformat, urlvars...)
 }
 
 func (s *OAuthSession) Patch(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
-	surl := ourl(urlformat, urlvars...)
+	return s.do(PATCH, params, urlformat, urlvars...)
+}
+
+// Put performs a PUT request against the OAuth API.
+func (s *OAuthSession) Put(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
+	return s.do(PUT, params, urlformat, urlvars...)
+}
+
+// Del performs a DELETE request against the OAuth API. It is not named
+// Delete, which deletes submissions and comments.
+func (s *OAuthSession) Del(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
+	return s.do(DELETE, params, urlformat, urlvars...)
+}
+
+// do performs a request with the given HTTP method against the OAuth API.
+func (s *OAuthSession) do(action method, params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
+	return s.send(&oauthRequest{
+		url:    s.endpoints.ourl(urlformat, urlvars...),
+		action: action,
+		values: params,
+	})
+}
+
+// send sends req, see authorize.
+func (s *OAuthSession) send(req *oauthRequest) (*bytes.Buffer, error) {
+	var body *bytes.Buffer
+	err := s.authorize(req, func() (err error) {
+		body, err = req.getResponse()
+		return err
+	})
+	return body, err
+}
+
+// getJSON performs a GET request against the OAuth API and decodes the
+// response into v as it is read.
+func (s *OAuthSession) getJSON(v interface{}, params *url.Values, urlformat string, urlvars ...interface{}) error {
 	req := &oauthRequest{
-		accessToken: s.accessToken,
-		url:         surl,
-		useragent:   s.useragent,
-		action:      PATCH,
-		values:      params,
+		url:    s.endpoints.ourl(urlformat, urlvars...),
+		action: GET,
+		values: params,
 	}
-	return req.getResponse()
+	return s.authorize(req, func() error {
+		return req.decode(v)
+	})
 }
 
-func (s *OAuthSession) Me() (*OARedditor, error) {
+// authorize fills in the credentials and plumbing of req, then calls send
+// to send it. If reddit rejects the access token, it is renewed and send
+// is called once more, with req marked as a retry.
+func (s *OAuthSession) authorize(req *oauthRequest, send func() error) error {
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+	req.accessToken = token
+	req.useragent = s.useragent
+	req.log = s.log
+	req.doer = s.doer()
+	req.flights = s.flights
+	err = send()
+	if serr, ok := err.(*StatusError); ok && serr.StatusCode == http.StatusUnauthorized {
+		if req.accessToken, err = s.tokenRejected(token); err != nil {
+			return err
+		}
+		req.retry = true
+		err = send()
+	}
+	return err
+}
+
+// apiPost posts to an endpoint that understands api_type=json, see apiPost.
+func (s *OAuthSession) apiPost(params *url.Values, urlformat string, urlvars ...interface{}) (json.RawMessage, error) {
+	return apiPost(s, params, urlformat, urlvars...)
+}
+
+// Me returns an up-to-date redditor object of the logged-in user.
+func (s *OAuthSession) Me() (*Redditor, error) {
+	if err := s.requireScope(IdentityScope); err != nil {
+		return nil, err
+	}
 	body, err := s.Get(nil, "/api/v1/me")
 	if err != nil {
 		return nil, err
 	}
 
-	oresp := &OARedditor{}
-	log.Println(body.String())
+	oresp := &Redditor{}
 	err = json.NewDecoder(body).Decode(oresp)
 	if err != nil {
 		return nil, err
 	}
 	// put the session in it
-	oresp.session = s
+	oresp.client = s
+	oresp.self = true
 
 	return oresp, nil
 }
 
-func (s *OAuthSession) User(username string) (*OARedditor, error) {
+// User returns a Redditor for the given username.
+func (s *OAuthSession) User(username string) (*Redditor, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
 	body, err := s.Get(nil, "/user/%s/about", username)
 	if err != nil {
 		return nil, err
 	}
 
 	type Resp struct {
-		Kind string     `json:"kind"`
-		Data OARedditor `json:"data"`
+		Kind string   `json:"kind"`
+		Data Redditor `json:"data"`
 	}
 
 	oresp := &Resp{}
-	log.Println(body.String())
 	err = json.NewDecoder(body).Decode(oresp)
 	if err != nil {
 		return nil, err
 	}
 	// put the session in it
-	oresp.Data.session = s
-
-	log.Println(oresp.Data.String())
+	oresp.Data.client = s
 
 	return &oresp.Data, nil
 }
+
+// AboutRedditor returns a Redditor for the given username. It is the same
+// as User, under the name Session uses.
+func (s *OAuthSession) AboutRedditor(username string) (*Redditor, error) {
+	return s.User(username)
+}
+
+// AboutSubreddit returns a subreddit for the given subreddit name.
+func (s *OAuthSession) AboutSubreddit(subreddit string) (*Subreddit, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/r/%s/about", subreddit)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data Subreddit
+	}
+	r := new(Response)
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+	r.Data.web = s.Endpoints().WWW
+	return &r.Data, nil
+}
+
+// DefaultFrontpage returns the submissions on the logged-in user's frontpage.
+func (s *OAuthSession) DefaultFrontpage(sort popularitySort, params ListingOptions) ([]*Submission, error) {
+	return s.SubredditSubmissions("", sort, params)
+}
+
+// SubredditSubmissions returns the submissions on the given subreddit, or on
+// the frontpage if subreddit is empty.
+func (s *OAuthSession) SubredditSubmissions(subreddit string, sort popularitySort, params ListingOptions) ([]*Submission, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	v, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data struct {
+			Children []struct {
+				Data *Submission
+			}
+		}
+	}
+	r := new(Response)
+	if subreddit == "" {
+		err = s.getJSON(r, &v, "/%s", sort)
+	} else {
+		err = s.getJSON(r, &v, "/r/%s/%s", subreddit, sort)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	submissions := make([]*Submission, len(r.Data.Children))
+	web := s.Endpoints().WWW
+	for i, child := range r.Data.Children {
+		child.Data.web = web
+		submissions[i] = child.Data
+	}
+	return submissions, nil
+}
+
+// Comments returns the comments for a given Submission.
+func (s *OAuthSession) Comments(h *Submission) ([]*Comment, error) {
+	if err := s.requireScope(ReadScope); err != nil {
+		return nil, err
+	}
+	var page commentsPage
+	if err := s.getJSON(&page, nil, "/comments/%s", h.ID); err != nil {
+		return nil, err
+	}
+	return page.comments(s.Endpoints().WWW), nil
+}
+
+// Submit submits a link or text post.
+func (s *OAuthSession) Submit(ns *newSubmission) error {
+	if err := s.requireScope(SubmitScope); err != nil {
+		return err
+	}
+	v := &url.Values{
+		"sr":          {ns.Subreddit},
+		"title":       {ns.Title},
+		"sendreplies": {strconv.FormatBool(ns.SendReplies)},
+		"resubmit":    {strconv.FormatBool(ns.Resubmit)},
+	}
+	if ns.Self {
+		v.Set("kind", "self")
+		v.Set("text", ns.Content)
+	} else {
+		v.Set("kind", "link")
+		v.Set("url", ns.Content)
+	}
+	if ns.Captcha != nil && ns.Captcha.Iden != "" {
+		v.Set("iden", ns.Captcha.Iden)
+		v.Set("captcha", ns.Captcha.Response)
+	}
+	_, err := s.apiPost(v, "/api/submit")
+	return err
+}
+
+// Vote either votes or rescinds a vote for a Submission or Comment.
+func (s *OAuthSession) Vote(v Voter, dir vote) error {
+	if err := s.requireScope(VoteScope); err != nil {
+		return err
+	}
+	_, err := s.Post(&url.Values{
+		"id":  {v.voteID()},
+		"dir": {string(dir)},
+	}, "/api/vote")
+	return err
+}
+
+// Reply posts a comment as a response to a Submission or Comment.
+func (s *OAuthSession) Reply(r Replier, comment string) error {
+	if err := s.requireScope(SubmitScope); err != nil {
+		return err
+	}
+	_, err := s.apiPost(&url.Values{
+		"thing_id": {r.replyID()},
+		"text":     {comment},
+	}, "/api/comment")
+	return err
+}
+
+// Delete deletes a Submission or Comment.
+func (s *OAuthSession) Delete(d Deleter) error {
+	if err := s.requireScope(EditScope); err != nil {
+		return err
+	}
+	_, err := s.Post(&url.Values{
+		"id": {d.deleteID()},
+	}, "/api/del")
+	return err
+}
@@ -0,0 +1,138 @@
+package geddit
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// tokenCounter counts the access token requests going through it.
+func tokenCounter(n *int32) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/api/v1/access_token" {
+				atomic.AddInt32(n, 1)
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+func newTestOAuthSession(t *testing.T, grants *int32) (*OAuthSession, *geddittest.Server) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret,
+		WithHTTPClient(srv.Client()), WithMiddleware(tokenCounter(grants)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, srv
+}
+
+// meConcurrently calls s.Me n times at once and returns the errors.
+func meConcurrently(s *OAuthSession, n int) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.Me()
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestOAuthSessionRenewsExpiredTokenOnce(t *testing.T) {
+	var grants int32
+	s, _ := newTestOAuthSession(t, &grants)
+
+	s.tokenMu.Lock()
+	s.expiry = time.Now()
+	s.tokenMu.Unlock()
+
+	for _, err := range meConcurrently(s, 20) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&grants); n != 2 {
+		t.Errorf("got %d token requests, want 2", n)
+	}
+	if s.Token().Expired() {
+		t.Error("token is still expired")
+	}
+}
+
+func TestOAuthSessionRenewsRejectedTokenOnce(t *testing.T) {
+	var grants int32
+	s, srv := newTestOAuthSession(t, &grants)
+	old := s.Token().AccessToken
+
+	srv.ExpireTokens()
+	for _, err := range meConcurrently(s, 20) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&grants); n != 2 {
+		t.Errorf("got %d token requests, want 2", n)
+	}
+	if s.Token().AccessToken == old {
+		t.Error("token was not renewed")
+	}
+}
+
+func TestOAuthSessionConcurrentRequestsDuringRefresh(t *testing.T) {
+	var grants int32
+	s, srv := newTestOAuthSession(t, &grants)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				srv.ExpireTokens()
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				if _, err := s.Me(); err != nil {
+					errs <- err
+					return
+				}
+				s.HasScope(IdentityScope)
+				s.Token()
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	close(errs)
+
+	// A request may be rejected again right after its retry renewed the
+	// token, so only the session's state is checked here.
+	for err := range errs {
+		if serr, ok := err.(*StatusError); !ok || serr.StatusCode != http.StatusUnauthorized {
+			t.Error(err)
+		}
+	}
+	if atomic.LoadInt32(&grants) < 2 {
+		t.Error("token was never renewed")
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoHealthyAccount is returned by a Pool when all its accounts are
+// unhealthy, or it has none.
+var ErrNoHealthyAccount = errors.New("pool: no healthy account")
+
+const (
+	// maxAuthFailures is how many authentication failures in a row make a
+	// pooled account unhealthy.
+	maxAuthFailures = 3
+	// defaultQuota is the number of requests reddit allows an OAuth client
+	// per rate limit window, assumed until it reports the actual quota.
+	defaultQuota = 600
+)
+
+// Pool holds the OAuth sessions of several accounts, each with its own
+// rate limit budget. Reads are dispatched to the healthy account with the
+// most remaining quota, while writes go to the account given to Session.
+//
+// An account becomes unhealthy after repeated authentication failures, or
+// when CheckHealth finds it suspended, and stays so until CheckHealth finds
+// it working again. A Pool is safe for concurrent use.
+type Pool struct {
+	mu       sync.Mutex
+	accounts []*poolAccount
+	byName   map[string]*poolAccount
+}
+
+type poolAccount struct {
+	session *OAuthSession
+
+	// remaining is the request quota left until reset, as last reported
+	// by reddit minus the picks since.
+	remaining    float64
+	reset        time.Time
+	requests     int
+	errors       int
+	authFailures int
+	suspended    bool
+	lastErr      error
+}
+
+// AccountStats describes an account of a Pool.
+type AccountStats struct {
+	Username string
+	// Requests and Errors count the HTTP requests of the account, Errors
+	// counting the ones that failed or got a 4xx or 5xx status.
+	Requests int
+	Errors   int
+	// AuthFailures is the number of authentication failures in a row.
+	AuthFailures int
+	// RateLimitRemaining is the quota left until RateLimitReset, as last
+	// reported by reddit minus the reads picked since.
+	RateLimitRemaining float64
+	RateLimitReset     time.Time
+	Suspended          bool
+	Healthy            bool
+	LastError          error
+}
+
+// NewPool creates a Pool of the given sessions, see Add.
+func NewPool(sessions ...*OAuthSession) *Pool {
+	p := &Pool{byName: make(map[string]*poolAccount)}
+	for _, s := range sessions {
+		p.Add(s)
+	}
+	return p
+}
+
+// Add adds a session to the pool, under its username, replacing the
+// previous session of the same account if any. The first time a session
+// is added, Add installs a middleware on it with Use to track its rate
+// limit and failures. Use is not safe while the session makes requests, so
+// a session must be added before it is used, and to a single pool. The
+// middleware stays installed: it stops tracking the session once it is
+// removed or replaced, and resumes if it is added again.
+func (p *Pool) Add(s *OAuthSession) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s.pool != p {
+		s.Use(p.track(s))
+		s.pool = p
+	}
+	if old, ok := p.byName[s.username]; ok {
+		p.removeLocked(old)
+	}
+	a := &poolAccount{session: s, remaining: defaultQuota}
+	p.byName[s.username] = a
+	p.accounts = append(p.accounts, a)
+}
+
+// Remove removes the account with the given username from the pool.
+func (p *Pool) Remove(username string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a, ok := p.byName[username]; ok {
+		p.removeLocked(a)
+		delete(p.byName, username)
+	}
+}
+
+func (p *Pool) removeLocked(a *poolAccount) {
+	for i, b := range p.accounts {
+		if b == a {
+			p.accounts = append(p.accounts[:i], p.accounts[i+1:]...)
+			return
+		}
+	}
+}
+
+// Session returns the session of the given account, to pin writes to it.
+// It fails if the account is not in the pool or is unhealthy.
+func (p *Pool) Session(username string) (*OAuthSession, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	a, ok := p.byName[username]
+	if !ok {
+		return nil, fmt.Errorf("pool: no account %q", username)
+	}
+	if !a.healthy() {
+		return nil, fmt.Errorf("pool: account %q is unhealthy", username)
+	}
+	return a.session, nil
+}
+
+// Pick returns the session of the healthy account with the most remaining
+// quota, to make a read with. Until reddit reports the quota of an account,
+// and again once its rate limit window is over, it is assumed to be 600
+// requests.
+func (p *Pool) Pick() (*OAuthSession, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var best *poolAccount
+	for _, a := range p.accounts {
+		if !a.reset.IsZero() && now.After(a.reset) {
+			a.remaining = defaultQuota
+			a.reset = time.Time{}
+		}
+		if a.healthy() && (best == nil || a.remaining > best.remaining) {
+			best = a
+		}
+	}
+	if best == nil {
+		return nil, ErrNoHealthyAccount
+	}
+	// Spend one request of the budget right away, so that concurrent
+	// picks spread over the accounts before reddit reports their quota.
+	best.remaining--
+	return best.session, nil
+}
+
+// Stats returns the state of the accounts of the pool, sorted by username.
+func (p *Pool) Stats() []AccountStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]AccountStats, len(p.accounts))
+	for i, a := range p.accounts {
+		stats[i] = AccountStats{
+			Username:           a.session.username,
+			Requests:           a.requests,
+			Errors:             a.errors,
+			AuthFailures:       a.authFailures,
+			RateLimitRemaining: a.remaining,
+			RateLimitReset:     a.reset,
+			Suspended:          a.suspended,
+			Healthy:            a.healthy(),
+			LastError:          a.lastErr,
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Username < stats[j].Username
+	})
+	return stats
+}
+
+// CheckHealth fetches every account's identity, marking the account
+// suspended if reddit says so and healthy again if it works. It returns the
+// first error met, after checking all accounts.
+func (p *Pool) CheckHealth() error {
+	p.mu.Lock()
+	accounts := append([]*poolAccount(nil), p.accounts...)
+	p.mu.Unlock()
+
+	var first error
+	for _, a := range accounts {
+		me, err := a.session.Me()
+		p.mu.Lock()
+		if err == nil {
+			a.suspended = me.IsSuspended
+			a.authFailures = 0
+		} else {
+			a.lastErr = err
+			if first == nil {
+				first = err
+			}
+		}
+		p.mu.Unlock()
+	}
+	return first
+}
+
+func (a *poolAccount) healthy() bool {
+	return !a.suspended && a.authFailures < maxAuthFailures
+}
+
+// track returns the middleware recording the requests of s in its account,
+// as long as s is the session of an account of the pool.
+func (p *Pool) track(s *OAuthSession) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.Do(req)
+
+			authFailed := false
+			if err == nil {
+				authFailed = resp.StatusCode == http.StatusUnauthorized
+				if resp.StatusCode == http.StatusOK && strings.HasSuffix(req.URL.Path, "/api/v1/access_token") {
+					authFailed = tokenFailed(resp)
+				}
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			a, ok := p.byName[s.username]
+			if !ok || a.session != s {
+				return resp, err
+			}
+			a.requests++
+			switch {
+			case err != nil:
+				a.errors++
+				a.lastErr = err
+				return nil, err
+			case authFailed:
+				a.authFailures++
+			case resp.StatusCode < 400:
+				a.authFailures = 0
+			}
+			if resp.StatusCode >= 400 || authFailed {
+				a.errors++
+				a.lastErr = fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+			}
+			if v, perr := strconv.ParseFloat(resp.Header.Get("X-Ratelimit-Remaining"), 64); perr == nil {
+				a.remaining = v
+				if reset, perr := strconv.Atoi(resp.Header.Get("X-Ratelimit-Reset")); perr == nil {
+					a.reset = time.Now().Add(time.Duration(reset) * time.Second)
+				}
+			}
+			return resp, nil
+		})
+	}
+}
+
+// tokenFailed reports whether an access token response is a failed grant,
+// which reddit answers with a 200. It puts the body back for the session.
+func tokenFailed(resp *http.Response) bool {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	var r struct {
+		Error string `json:"error"`
+	}
+	return json.Unmarshal(body, &r) == nil && r.Error != ""
+}
+
+// DefaultFrontpage implements Reader with the account picked by Pick.
+func (p *Pool) DefaultFrontpage(sort PopularitySort, params ListingOptions) ([]*Submission, error) {
+	s, err := p.Pick()
+	if err != nil {
+		return nil, err
+	}
+	return s.DefaultFrontpage(sort, params)
+}
+
+// SubredditSubmissions implements Reader with the account picked by Pick.
+func (p *Pool) SubredditSubmissions(subreddit string, sort PopularitySort, params ListingOptions) ([]*Submission, error) {
+	s, err := p.Pick()
+	if err != nil {
+		return nil, err
+	}
+	return s.SubredditSubmissions(subreddit, sort, params)
+}
+
+// AboutSubreddit implements Reader with the account picked by Pick.
+func (p *Pool) AboutSubreddit(subreddit string) (*Subreddit, error) {
+	s, err := p.Pick()
+	if err != nil {
+		return nil, err
+	}
+	return s.AboutSubreddit(subreddit)
+}
+
+// AboutRedditor implements Reader with the account picked by Pick. The
+// redditor stays bound to that account's session.
+func (p *Pool) AboutRedditor(username string) (*Redditor, error) {
+	s, err := p.Pick()
+	if err != nil {
+		return nil, err
+	}
+	return s.AboutRedditor(username)
+}
+
+// Comments implements Reader with the account picked by Pick.
+func (p *Pool) Comments(h *Submission) ([]*Comment, error) {
+	s, err := p.Pick()
+	if err != nil {
+		return nil, err
+	}
+	return s.Comments(h)
+}
@@ -0,0 +1,226 @@
+package geddit
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// newPoolSession returns a session of username on its own geddittest
+// server, whose rate limit is limit requests an hour.
+func newPoolSession(t *testing.T, username string, limit int) (*OAuthSession, *geddittest.Server) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	srv.SetRateLimit(limit, time.Hour)
+	s, err := NewOAuthSession(username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, srv
+}
+
+// poolStats returns the stats of the account of p with the given username.
+func poolStats(t *testing.T, p *Pool, username string) AccountStats {
+	for _, st := range p.Stats() {
+		if st.Username == username {
+			return st
+		}
+	}
+	t.Fatalf("no account %q in the pool", username)
+	return AccountStats{}
+}
+
+func TestPoolPickOrdering(t *testing.T) {
+	a, _ := newPoolSession(t, geddittest.Username, 100)
+	b, _ := newPoolSession(t, geddittest.Moderator, 500)
+	p := NewPool(a, b)
+
+	// Until reddit reports their quota, picks alternate between accounts.
+	for i, want := range []*OAuthSession{a, b, a, b} {
+		if s, err := p.Pick(); err != nil || s != want {
+			t.Fatalf("pick %d: got %v, %v, want %s", i, s, err, want.username)
+		}
+	}
+
+	// Then they go to the account with the most quota left.
+	for _, s := range []*OAuthSession{a, b} {
+		if _, err := s.Me(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ra, rb := poolStats(t, p, a.username).RateLimitRemaining, poolStats(t, p, b.username).RateLimitRemaining; ra >= rb {
+		t.Fatalf("got remaining quotas %v and %v", ra, rb)
+	}
+	for i := 0; i < 3; i++ {
+		if s, err := p.Pick(); err != nil || s != b {
+			t.Fatalf("got %v, %v, want %s", s, err, b.username)
+		}
+	}
+
+	p.Remove(b.username)
+	if s, err := p.Pick(); err != nil || s != a {
+		t.Fatalf("got %v, %v after removing %s", s, err, b.username)
+	}
+	if _, err := p.Session(b.username); err == nil {
+		t.Errorf("got the session of removed account %s", b.username)
+	}
+}
+
+func TestPoolTracksSessionsOnce(t *testing.T) {
+	a, _ := newPoolSession(t, geddittest.Username, 600)
+	p := NewPool(a)
+	installed := len(a.middleware)
+	p.Add(a)
+	if len(a.middleware) != installed {
+		t.Errorf("adding the session again installed %d more middlewares", len(a.middleware)-installed)
+	}
+	if _, err := a.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if n := poolStats(t, p, a.username).Requests; n != 1 {
+		t.Errorf("got %d requests after adding the session twice, want 1", n)
+	}
+
+	p.Remove(a.username)
+	if _, err := a.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.Stats()); n != 0 {
+		t.Fatalf("got %d accounts after removing the only one", n)
+	}
+	// The pool keeps no reference to removed sessions.
+	if len(p.byName) != 0 || len(p.accounts) != 0 {
+		t.Errorf("pool still holds %v after removing %s", p.byName, a.username)
+	}
+
+	p.Add(a)
+	if len(a.middleware) != installed {
+		t.Errorf("adding the session back installed %d more middlewares", len(a.middleware)-installed)
+	}
+	if _, err := a.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if n := poolStats(t, p, a.username).Requests; n != 1 {
+		t.Errorf("got %d requests after adding the session back, want 1", n)
+	}
+
+	// A replaced session is not tracked anymore.
+	other, _ := newPoolSession(t, geddittest.Username, 600)
+	p.Add(other)
+	if _, err := a.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if n := poolStats(t, p, a.username).Requests; n != 0 {
+		t.Errorf("got %d requests of the replaced session, want 0", n)
+	}
+}
+
+func TestPoolHealthTransitions(t *testing.T) {
+	a, srvA := newPoolSession(t, geddittest.Username, 600)
+	b, _ := newPoolSession(t, geddittest.Moderator, 600)
+	p := NewPool(a, b)
+
+	// Rejected tokens that cannot be renewed make the account unhealthy.
+	srvA.Inject(geddittest.Fault{Path: "/api/v1/access_token", Status: http.StatusUnauthorized})
+	srvA.ExpireTokens()
+	for i := 0; i < maxAuthFailures && poolStats(t, p, a.username).Healthy; i++ {
+		if _, err := a.Me(); err == nil {
+			t.Fatal("got no error with a rejected token")
+		}
+	}
+	st := poolStats(t, p, a.username)
+	if st.Healthy || st.AuthFailures < maxAuthFailures || st.LastError == nil {
+		t.Fatalf("got stats %+v after repeated authentication failures", st)
+	}
+	for i := 0; i < 3; i++ {
+		if s, err := p.Pick(); err != nil || s != b {
+			t.Fatalf("got %v, %v, want the healthy account", s, err)
+		}
+	}
+	if _, err := p.Session(a.username); err == nil {
+		t.Error("got the session of an unhealthy account")
+	}
+
+	p.Remove(b.username)
+	if _, err := p.Pick(); err != ErrNoHealthyAccount {
+		t.Fatalf("got %v with no healthy account, want ErrNoHealthyAccount", err)
+	}
+
+	// CheckHealth finds it working again.
+	if err := p.CheckHealth(); err == nil {
+		t.Error("got no error checking a failing account")
+	}
+	srvA.ClearFaults()
+	if err := p.CheckHealth(); err != nil {
+		t.Fatal(err)
+	}
+	if st := poolStats(t, p, a.username); !st.Healthy || st.AuthFailures != 0 {
+		t.Errorf("got stats %+v after a successful health check", st)
+	}
+	if s, err := p.Pick(); err != nil || s != a {
+		t.Errorf("got %v, %v, want the recovered account", s, err)
+	}
+}
+
+func TestPoolConcurrentUse(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	var sessions []*OAuthSession
+	for _, name := range []string{geddittest.Username, geddittest.Moderator} {
+		s, err := NewOAuthSession(name, geddittest.Password, "geddit tests",
+			geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, s)
+	}
+	p := NewPool(sessions...)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := p.AboutSubreddit("golang"); err != nil {
+					errs <- err
+					return
+				}
+				p.Stats()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			p.Remove(geddittest.Moderator)
+			p.Add(sessions[1])
+			if err := p.CheckHealth(); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	total := 0
+	for _, st := range p.Stats() {
+		if !st.Healthy {
+			t.Errorf("account %s is unhealthy: %v", st.Username, st.LastError)
+		}
+		total += st.Requests
+	}
+	if total > srv.Requests() {
+		t.Errorf("pool counted %d requests, the server got %d", total, srv.Requests())
+	}
+}
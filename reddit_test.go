@@ -1,137 +1,88 @@
 package geddit
 
 import (
-	"flag"
-	"fmt"
-	"github.com/toqueteos/webbrowser"
-	"io/ioutil"
-	"os"
-	"path"
-	"strings"
 	"testing"
-	"time"
-)
 
-var (
-	testall bool
+	"github.com/jzelinskie/geddit/geddittest"
 )
 
-func init() {
-	flag.BoolVar(&testall, "all", false, "-all\n Test everything.")
-	flag.Parse()
+// newTestLoginSession logs into a geddittest server with a password and a
+// session cookie, like NewLoginSession does against reddit.
+func newTestLoginSession(t *testing.T) (*LoginSession, *geddittest.Server) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	setDefaultClient(t, srv.Client())
+
+	session, err := NewLoginSession(geddittest.Username, geddittest.Password, "geddit tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session, srv
+}
+
+// setDefaultClient makes sessions that were not given a client use d for
+// the rest of the test, since NewLoginSession logs in before it can be
+// given one.
+func setDefaultClient(t *testing.T, d Doer) {
+	client := defaultClient
+	defaultClient = d
+	t.Cleanup(func() { defaultClient = client })
 }
 
 func TestSubmit(t *testing.T) {
+	session, srv := newTestLoginSession(t)
 
-	if !testall {
-		return
+	subreddit, err := session.AboutSubreddit("golang")
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	session, err := NewLoginSession(
-		"redditgolang",
-		"apitest11",
-		"tester",
-	)
+	needsCaptcha, err := session.NeedsCaptcha()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	if needsCaptcha {
+		t.Fatal("got a captcha request")
 	}
 
-	subreddit, err := session.AboutSubreddit("mybottester")
+	err = session.Submit(NewTextSubmission(subreddit.Name, "TESTING TEXT", "TEST TEXT", true, &Captcha{}))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-
-	needsCaptcha, err := session.NeedsCaptcha()
+	err = session.Submit(NewLinkSubmission(subreddit.Name, "TESTING LINK", "https://github.com/jzelinskie/geddit", true, &Captcha{}))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	t.Log("Needs captcha:", needsCaptcha)
-
-	if needsCaptcha {
-		iden, err := session.NewCaptchaIden()
-		if err != nil {
-			t.Error(err)
-		}
-
-		stat, _ := os.Stdin.Stat()
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			fmt.Println("data is being piped to stdin")
-		} else {
-			fmt.Println("stdin is from a terminal")
-		}
-
-		//_, err = session.CaptchaImage(iden)
-		//if err != nil {
-		//	t.Error(err)
-		//}
-		webbrowser.Open("http://www.reddit.com/captcha/" + iden)
-		fmt.Println("Enter captcha in captcha.txt")
-
-		ind0 := strings.Index(os.Args[0], "github.com")
-		ind1 := strings.Index(os.Args[0], "/_test")
-		p0 := os.Getenv("GOPATH")
-		p0 = path.Join(p0, "src", os.Args[0][ind0:ind1], "captcha.txt")
-		fmt.Println(p0)
-		time.Sleep(time.Second * 7)
-		for {
-			if _, err = os.Stat(p0); err != nil {
-				time.Sleep(time.Second * 3)
-			} else {
-				break
-			}
-		}
-		bb, err := ioutil.ReadFile(p0)
-		if err != nil {
-			t.Error(err)
-		}
-		captcha := strings.TrimSpace(string(bb))
-		fmt.Println("CAPTCHA:", captcha)
-		os.Remove(p0)
-
-		err = session.Submit(NewTextSubmission(subreddit.Name, "CAPTCHA TESTING TEXT", "TEST TEXT", true, &Captcha{iden, captcha}))
-		if err != nil {
-			t.Error(err)
-		}
-
-		// there is a submission delay!
-		// needs to find a way to test both without the time delay, maybe two accounts
-		//
-		//err = session.Submit(NewLinkSubmission(subreddit.Name, "CAPTCHA TESTING LINK", "https://github.com/jzelinskie/geddit", true, &Captcha{iden, captcha}))
-		//if err != nil {
-		//	t.Error(err)
-		//}
-
-	} else {
-
-		err = session.Submit(NewTextSubmission(subreddit.Name, "TESTING TEXT", "TEST TEXT", true, &Captcha{}))
-		if err != nil {
-			t.Error(err)
-		}
-
-		// there is a submission delay!
-		// needs to find a way to test both without the time delay, maybe two accounts
-		//
-		//err = session.Submit(NewLinkSubmission(subreddit.Name, "TESTING LINK", "https://github.com/jzelinskie/geddit", true, &Captcha{}))
-		//if err != nil {
-		//	t.Error(err)
-		//}
+	links := srv.Links(subreddit.Name)
+	if len(links) < 2 {
+		t.Fatalf("got %d links", len(links))
+	}
+	text, link := links[len(links)-2], links[len(links)-1]
+	if text.Title != "TESTING TEXT" || text.Selftext != "TEST TEXT" || text.Author != geddittest.Username {
+		t.Errorf("got text submission %+v", text)
+	}
+	if link.Title != "TESTING LINK" || link.URL != "https://github.com/jzelinskie/geddit" {
+		t.Errorf("got link submission %+v", link)
 	}
-
 }
 
 func TestListings(t *testing.T) {
-	session, err := NewLoginSession(
-		"redditgolang",
-		"apitest11",
-		"tester",
-	)
+	session, _ := newTestLoginSession(t)
+
+	saved, err := session.MySaved(NewSubmissions, "")
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(saved) != 0 {
+		t.Errorf("got %d saved submissions, want 0", len(saved))
+	}
 
-	_, err = session.MySaved(NewSubmissions, "")
+	submitted, err := session.MySubmitted(NewSubmissions, "")
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(submitted) != 1 || submitted[0].ID != "l1" {
+		t.Errorf("got submitted %v, want l1", submitted)
+	}
 }
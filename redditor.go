@@ -6,23 +6,59 @@ package geddit
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	//"log"
+	"io"
 	"net/url"
 	"strconv"
+	"time"
 )
 
+// Redditor represents a reddit user. Redditors returned by a session are
+// bound to it, so their listings and actions go through the same session
+// whether it is a Session, a LoginSession or an OAuthSession.
 type Redditor struct {
-	ID           string  `json:"id"`
-	Name         string  `json:"name"`
-	LinkKarma    int     `json:"link_karma"`
-	CommentKarma int     `json:"comment_karma"`
-	Created      float32 `json:"created_utc"`
-	Gold         bool    `json:"is_gold"`
-	Mod          bool    `json:"is_mod"`
-	Mail         *bool   `json:"has_mail"`
-	ModMail      *bool   `json:"has_mod_mail"`
-	InboxCount   int     `json:"inbox_count"`
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	LinkKarma        int       `json:"link_karma"`
+	CommentKarma     int       `json:"comment_karma"`
+	Created          time.Time `json:"-"`
+	IconImg          string    `json:"icon_img"`
+	Gold             bool      `json:"is_gold"`
+	Mod              bool      `json:"is_mod"`
+	Verified         bool      `json:"verified"`
+	HasVerifiedEmail *bool     `json:"has_verified_email"`
+	IsSuspended      bool      `json:"is_suspended"`
+	Mail             *bool     `json:"has_mail"`
+	ModMail          *bool     `json:"has_mod_mail"`
+	InboxCount       int       `json:"inbox_count"`
+
+	client Client
+	// self is set on redditors returned by Me, which are the logged-in
+	// user of client.
+	self bool
+}
+
+// OARedditor is the former name of Redditor for users obtained through an
+// OAuthSession.
+//
+// Deprecated: use Redditor.
+type OARedditor = Redditor
+
+// UnmarshalJSON decodes a redditor, converting its creation date.
+func (r *Redditor) UnmarshalJSON(b []byte) error {
+	type redditor Redditor
+	aux := struct {
+		*redditor
+		Created *float64 `json:"created_utc"`
+	}{redditor: (*redditor)(r)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if aux.Created != nil {
+		r.Created = time.Unix(int64(*aux.Created), 0).UTC()
+	}
+	return nil
 }
 
 // String returns the string representation of a reddit user.
@@ -30,48 +66,123 @@ func (r *Redditor) String() string {
 	return fmt.Sprintf("%s (%d-%d)", r.Name, r.LinkKarma, r.CommentKarma)
 }
 
-// OAuth Redditor
-type OARedditor struct {
-	ID           string  `json:"id"`
-	Name         string  `json:"name"`
-	LinkKarma    int     `json:"link_karma"`
-	CommentKarma int     `json:"comment_karma"`
-	Created      float32 `json:"created_utc"`
-	Gold         bool    `json:"is_gold"`
-	Mod          bool    `json:"is_mod"`
-	Mail         *bool   `json:"has_mail"`
-	ModMail      *bool   `json:"has_mod_mail"`
-	InboxCount   int     `json:"inbox_count"`
-	//
-	session *OAuthSession `json:"-"`
+// errUnbound is returned by the methods of a Redditor that was not obtained
+// from a session.
+var errUnbound = errors.New("geddit: redditor is not bound to a session")
+
+// errNotMe is returned by the methods of a Redditor that only make sense for
+// the logged-in user, when it was not obtained from Me.
+var errNotMe = errors.New("geddit: redditor is not the logged-in user")
+
+// Bind sets the session used by the methods of the redditor, which is needed
+// for redditors that were not obtained from a session.
+func (r *Redditor) Bind(c Client) {
+	r.client = c
 }
 
-// String returns the string representation of a reddit user.
-func (r *OARedditor) String() string {
-	return fmt.Sprintf("%s (%d-%d)", r.Name, r.LinkKarma, r.CommentKarma)
+// SendMessage sends a private message to the user.
+func (r *Redditor) SendMessage(subject, text string) error {
+	if r.client == nil {
+		return errUnbound
+	}
+	return sendMessage(r.client, r.Name, subject, text)
 }
 
-func (r *OARedditor) Submitted(hideVotedLinks bool, limit int, popsort popularitySort, agesort ageSort, params ...Param) ([]Submission, error) {
-	vals := url.Values{}
+// Friend adds the user to the logged-in user's friends.
+func (r *Redditor) Friend() error {
+	if r.client == nil {
+		return errUnbound
+	}
+	return friend(r.client, "", r.Name, FriendRelationship, FriendOptions{})
+}
+
+// Unfriend removes the user from the logged-in user's friends.
+func (r *Redditor) Unfriend() error {
+	if r.client == nil {
+		return errUnbound
+	}
+	return unfriend(r.client, "", r.Name, FriendRelationship)
+}
+
+// Block blocks the user for the logged-in user.
+func (r *Redditor) Block() error {
+	if r.client == nil {
+		return errUnbound
+	}
+	if err := requireScope(r.client, AccountScope); err != nil {
+		return err
+	}
+	_, err := r.client.Post(&url.Values{
+		"name": {r.Name},
+	}, "/api/block_user")
+	return err
+}
+
+// SubredditKarma is the karma a user earned in a single subreddit.
+type SubredditKarma struct {
+	Subreddit    string `json:"sr"`
+	LinkKarma    int    `json:"link_karma"`
+	CommentKarma int    `json:"comment_karma"`
+}
+
+// Karma returns the per-subreddit karma breakdown of the user. reddit only
+// gives it for the logged-in user, so it fails unless the redditor was
+// returned by Me.
+func (r *Redditor) Karma() ([]*SubredditKarma, error) {
+	if r.client == nil {
+		return nil, errUnbound
+	}
+	if !r.self {
+		return nil, errNotMe
+	}
+	if err := requireScope(r.client, MySubredditsScope); err != nil {
+		return nil, err
+	}
+	return karma(r.client)
+}
+
+// karma fetches the logged-in user's KarmaList through c.
+func karma(c Client) ([]*SubredditKarma, error) {
+	body, err := c.Get(nil, "/api/v1/me/karma")
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data []*SubredditKarma `json:"data"`
+	}
+	r := &Response{}
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+	return r.Data, nil
+}
+
+// Submitted returns the submissions of the user.
+func (r *Redditor) Submitted(hideVotedLinks bool, limit int, popsort popularitySort, agesort ageSort, params ...Param) ([]*Submission, error) {
+	vals := listingValues(popsort, agesort)
 	if !hideVotedLinks {
 		vals.Set("show", "all")
 	}
-	vals.Set("limit", strconv.Itoa(limit))
-	vals.Set("sort", string(popsort))
-	vals.Set("t", string(agesort))
-	vals.Set("username", r.Name)
+	if limit > 0 {
+		vals.Set("limit", strconv.Itoa(limit))
+	}
 	for _, v := range params {
 		vals.Set(v.Key, v.Value)
 	}
-	body, err := r.session.Get(&vals, "/user/%s/submitted", r.Name)
+	if r.client == nil {
+		return nil, errUnbound
+	}
+	if err := requireScope(r.client, HistoryScope); err != nil {
+		return nil, err
+	}
+	body, err := r.client.Get(&vals, "/user/%s/submitted", r.Name)
 	if err != nil {
 		return nil, err
 	}
 
-	//log.Println(body.String())
-
 	type SubContainer struct {
-		Data Submission `json:"data"`
+		Data *Submission `json:"data"`
 	}
 	type Resp struct {
 		Data struct {
@@ -87,9 +198,214 @@ func (r *OARedditor) Submitted(hideVotedLinks bool, limit int, popsort popularit
 		return nil, err
 	}
 
-	rs := make([]Submission, len(result.Data.Children))
+	web := webHost(r.client)
+	rs := make([]*Submission, len(result.Data.Children))
 	for k, v := range result.Data.Children {
+		v.Data.web = web
 		rs[k] = v.Data
 	}
 	return rs, nil
 }
+
+// listing returns an iterator over one of the user's profile listings.
+func (r *Redditor) listing(name string, popsort popularitySort, agesort ageSort, params ListingOptions, extra url.Values) *ProfileIterator {
+	vals := listingValues(popsort, agesort)
+	for k, v := range extra {
+		vals[k] = v
+	}
+	if r.client == nil {
+		return &ProfileIterator{err: errUnbound}
+	}
+	if err := requireScope(r.client, HistoryScope); err != nil {
+		return &ProfileIterator{err: err}
+	}
+	return &ProfileIterator{
+		iter: newListingIterator(r.client, params, vals, "/user/%s/%s", r.Name, name),
+	}
+}
+
+// Overview returns an iterator over the submissions and comments of the user.
+func (r *Redditor) Overview(popsort popularitySort, agesort ageSort, params ListingOptions) *ProfileIterator {
+	return r.listing("overview", popsort, agesort, params, nil)
+}
+
+// Comments returns an iterator over the comments of the user.
+func (r *Redditor) Comments(popsort popularitySort, agesort ageSort, params ListingOptions) *ProfileIterator {
+	return r.listing("comments", popsort, agesort, params, nil)
+}
+
+// Gilded returns an iterator over the gilded submissions and comments of
+// the user.
+func (r *Redditor) Gilded(params ListingOptions) *ProfileIterator {
+	return r.listing("gilded", DefaultPopularity, DefaultAge, params, nil)
+}
+
+// Upvoted returns an iterator over the things upvoted by the user. It is
+// only available for the logged-in user.
+func (r *Redditor) Upvoted(popsort popularitySort, agesort ageSort, params ListingOptions) *ProfileIterator {
+	return r.listing("upvoted", popsort, agesort, params, nil)
+}
+
+// Downvoted returns an iterator over the things downvoted by the user. It
+// is only available for the logged-in user.
+func (r *Redditor) Downvoted(popsort popularitySort, agesort ageSort, params ListingOptions) *ProfileIterator {
+	return r.listing("downvoted", popsort, agesort, params, nil)
+}
+
+// Hidden returns an iterator over the submissions hidden by the user. It is
+// only available for the logged-in user.
+func (r *Redditor) Hidden(popsort popularitySort, agesort ageSort, params ListingOptions) *ProfileIterator {
+	return r.listing("hidden", popsort, agesort, params, nil)
+}
+
+// Saved returns an iterator over the things saved by the user, optionally
+// restricted to submissions or comments. It is only available for the
+// logged-in user.
+func (r *Redditor) Saved(kind savedType, popsort popularitySort, agesort ageSort, params ListingOptions) *ProfileIterator {
+	var extra url.Values
+	if kind != SavedAll {
+		extra = url.Values{"type": {string(kind)}}
+	}
+	return r.listing("saved", popsort, agesort, params, extra)
+}
+
+// Trophies returns the trophies of the user.
+func (r *Redditor) Trophies() ([]*Trophy, error) {
+	if r.client == nil {
+		return nil, errUnbound
+	}
+	if err := requireScope(r.client, ReadScope); err != nil {
+		return nil, err
+	}
+	body, err := r.client.Get(nil, "/api/v1/user/%s/trophies", r.Name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTrophies(body)
+}
+
+// Trophy represents an award displayed on a user's profile.
+type Trophy struct {
+	ID        *string  `json:"id"`
+	AwardID   *string  `json:"award_id"`
+	Name      string   `json:"name"`
+	Desc      *string  `json:"description"`
+	URL       *string  `json:"url"`
+	Icon40    string   `json:"icon_40"`
+	Icon70    string   `json:"icon_70"`
+	GrantedAt *float64 `json:"granted_at"`
+}
+
+// String returns the string representation of a trophy.
+func (t *Trophy) String() string {
+	return t.Name
+}
+
+// decodeTrophies decodes a TrophyList.
+func decodeTrophies(body io.Reader) ([]*Trophy, error) {
+	type Response struct {
+		Data struct {
+			Trophies []struct {
+				Data *Trophy `json:"data"`
+			} `json:"trophies"`
+		} `json:"data"`
+	}
+	r := &Response{}
+	if err := json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+
+	trophies := make([]*Trophy, len(r.Data.Trophies))
+	for i, t := range r.Data.Trophies {
+		trophies[i] = t.Data
+	}
+	return trophies, nil
+}
+
+// listingValues returns the query parameters for the given sorts, leaving
+// out the ones that are not set so reddit applies its defaults.
+func listingValues(popsort popularitySort, agesort ageSort) url.Values {
+	vals := url.Values{}
+	if popsort != DefaultPopularity {
+		vals.Set("sort", string(popsort))
+	}
+	if agesort != DefaultAge {
+		vals.Set("t", string(agesort))
+	}
+	return vals
+}
+
+// ProfileItem is an entry of a listing mixing submissions and comments.
+// Exactly one of Submission and Comment is set, depending on Kind.
+type ProfileItem struct {
+	Kind       string
+	Submission *Submission
+	Comment    *Comment
+}
+
+// FullID returns the fullname of the submission or comment.
+func (p *ProfileItem) FullID() string {
+	if p.Submission != nil {
+		return p.Submission.FullID
+	}
+	return p.Comment.FullID
+}
+
+// ProfileIterator iterates over a user's profile listing, in the same
+// fashion as RelationshipIterator.
+type ProfileIterator struct {
+	iter *listingIterator
+	cur  *ProfileItem
+	err  error
+}
+
+// Next advances the iterator to the next item. It returns false when there
+// are no more items or an error occurred. Items of kinds other than
+// submissions and comments are skipped.
+func (it *ProfileIterator) Next() bool {
+	for it.err == nil {
+		t, ok := it.iter.next()
+		if !ok {
+			it.err = it.iter.err
+			return false
+		}
+
+		switch t.Kind {
+		case "t1":
+			c := &commentData{}
+			if it.err = json.Unmarshal(t.Data, c); it.err != nil {
+				return false
+			}
+			it.cur = &ProfileItem{Kind: t.Kind, Comment: c.comment(webHost(it.iter.client))}
+			return true
+		case "t3":
+			sub := &Submission{}
+			if it.err = json.Unmarshal(t.Data, sub); it.err != nil {
+				return false
+			}
+			sub.web = webHost(it.iter.client)
+			it.cur = &ProfileItem{Kind: t.Kind, Submission: sub}
+			return true
+		}
+	}
+	return false
+}
+
+// Item returns the item the iterator currently points to.
+func (it *ProfileIterator) Item() *ProfileItem {
+	return it.cur
+}
+
+// After returns the value to pass as ListingOptions.After to resume the
+// listing after the last page fetched.
+func (it *ProfileIterator) After() string {
+	if it.iter == nil {
+		return ""
+	}
+	return it.iter.after
+}
+
+// Err returns the first error encountered by the iterator.
+func (it *ProfileIterator) Err() error {
+	return it.err
+}
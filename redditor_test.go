@@ -0,0 +1,56 @@
+package geddit
+
+import (
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestRedditorFriendUsesMeFriends(t *testing.T) {
+	var grants int32
+	s, srv := newTestOAuthSession(t, &grants)
+
+	r, err := s.AboutRedditor(geddittest.Moderator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Friend(); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Friends(geddittest.Username); len(got) != 1 || got[0] != geddittest.Moderator {
+		t.Fatalf("got friends %v after Friend", got)
+	}
+	if err = r.Unfriend(); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Friends(geddittest.Username); len(got) != 0 {
+		t.Fatalf("got friends %v after Unfriend", got)
+	}
+
+	if err = s.Friend("", geddittest.Moderator, FriendRelationship, FriendOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Friends(geddittest.Username); len(got) != 1 {
+		t.Fatalf("got friends %v after OAuthSession.Friend", got)
+	}
+}
+
+func TestRedditorKarmaOnlyForMe(t *testing.T) {
+	var grants int32
+	s, _ := newTestOAuthSession(t, &grants)
+
+	r, err := s.AboutRedditor(geddittest.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Karma(); err != errNotMe {
+		t.Errorf("got %v for another redditor, want errNotMe", err)
+	}
+	me, err := s.Me()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !me.self {
+		t.Error("redditor returned by Me is not marked as the logged-in user")
+	}
+}
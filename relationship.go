@@ -0,0 +1,299 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// relationshipType represents the kinds of relationships a user can have
+// with a subreddit (or, for FriendRelationship, with the logged-in user).
+type relationshipType string
+
+const (
+	BannedRelationship          relationshipType = "banned"
+	MutedRelationship           relationshipType = "muted"
+	ContributorRelationship     relationshipType = "contributor"
+	ModeratorRelationship       relationshipType = "moderator"
+	ModeratorInviteRelationship relationshipType = "moderator_invite"
+	WikiBannedRelationship      relationshipType = "wikibanned"
+	WikiContributorRelationship relationshipType = "wikicontributor"
+	FriendRelationship          relationshipType = "friend"
+)
+
+// aboutPaths maps each listable relationship to its /r/{sub}/about/ page.
+var aboutPaths = map[relationshipType]string{
+	BannedRelationship:          "banned",
+	MutedRelationship:           "muted",
+	ContributorRelationship:     "contributors",
+	ModeratorRelationship:       "moderators",
+	WikiBannedRelationship:      "wikibanned",
+	WikiContributorRelationship: "wikicontributors",
+}
+
+// relationshipScope returns the OAuth scope needed to change a relationship.
+func relationshipScope(rel relationshipType) string {
+	switch rel {
+	case ModeratorRelationship, ModeratorInviteRelationship:
+		return ModOthersScope
+	case FriendRelationship:
+		return SubscribeScope
+	}
+	return ModContributorsScope
+}
+
+// Relationship represents a user's entry in one of a subreddit's user lists
+// (banned, muted, contributors, moderators, ...).
+type Relationship struct {
+	ID          string   `json:"rel_id"`
+	UserID      string   `json:"id"`
+	Name        string   `json:"name"`
+	Date        float64  `json:"date"`
+	Note        string   `json:"note"`
+	DaysLeft    *int     `json:"days_left"`
+	Permissions []string `json:"mod_permissions"`
+	FlairText   string   `json:"author_flair_text"`
+}
+
+// Added returns the time the relationship was created.
+func (r *Relationship) Added() time.Time {
+	return time.Unix(int64(r.Date), 0).UTC()
+}
+
+// String returns the string representation of a relationship.
+func (r *Relationship) String() string {
+	if r.DaysLeft != nil {
+		return fmt.Sprintf("%s (%d days left)", r.Name, *r.DaysLeft)
+	}
+	return r.Name
+}
+
+// RelationshipIterator iterates over a paginated list of relationships.
+// It is used like bufio.Scanner:
+//
+//	it := session.Banned("golang", geddit.ListingOptions{Limit: 100})
+//	for it.Next() {
+//		fmt.Println(it.Relationship())
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle error
+//	}
+type RelationshipIterator struct {
+	iter *listingIterator
+	cur  *Relationship
+	err  error
+}
+
+// Next advances the iterator to the next relationship, which will then be
+// available through Relationship. It returns false when there are no more
+// relationships or an error occurred.
+func (it *RelationshipIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	t, ok := it.iter.next()
+	if !ok {
+		it.err = it.iter.err
+		return false
+	}
+	r := &Relationship{}
+	if it.err = json.Unmarshal(t.Data, r); it.err != nil {
+		return false
+	}
+	it.cur = r
+	return true
+}
+
+// Relationship returns the relationship the iterator currently points to.
+func (it *RelationshipIterator) Relationship() *Relationship {
+	return it.cur
+}
+
+// After returns the fullname to pass as ListingOptions.After to resume the
+// listing after the last page fetched.
+func (it *RelationshipIterator) After() string {
+	if it.iter == nil {
+		return ""
+	}
+	return it.iter.after
+}
+
+// Err returns the first error encountered by the iterator.
+func (it *RelationshipIterator) Err() error {
+	return it.err
+}
+
+// Relationships returns an iterator over the users having the given
+// relationship with a subreddit.
+func (s *OAuthSession) Relationships(subreddit string, rel relationshipType, params ListingOptions) *RelationshipIterator {
+	if err := s.requireScope(ReadScope); err != nil {
+		return &RelationshipIterator{err: err}
+	}
+	path, ok := aboutPaths[rel]
+	if !ok {
+		return &RelationshipIterator{
+			err: fmt.Errorf("relationship %q cannot be listed", rel),
+		}
+	}
+	return &RelationshipIterator{
+		iter: newListingIterator(s, params, nil, "/r/%s/about/%s", subreddit, path),
+	}
+}
+
+// Banned returns an iterator over the users banned from a subreddit.
+func (s *OAuthSession) Banned(subreddit string, params ListingOptions) *RelationshipIterator {
+	return s.Relationships(subreddit, BannedRelationship, params)
+}
+
+// Muted returns an iterator over the users muted in a subreddit.
+func (s *OAuthSession) Muted(subreddit string, params ListingOptions) *RelationshipIterator {
+	return s.Relationships(subreddit, MutedRelationship, params)
+}
+
+// Contributors returns an iterator over the approved users of a subreddit.
+func (s *OAuthSession) Contributors(subreddit string, params ListingOptions) *RelationshipIterator {
+	return s.Relationships(subreddit, ContributorRelationship, params)
+}
+
+// Moderators returns an iterator over the moderators of a subreddit.
+func (s *OAuthSession) Moderators(subreddit string, params ListingOptions) *RelationshipIterator {
+	return s.Relationships(subreddit, ModeratorRelationship, params)
+}
+
+// WikiBanned returns an iterator over the users banned from a subreddit's wiki.
+func (s *OAuthSession) WikiBanned(subreddit string, params ListingOptions) *RelationshipIterator {
+	return s.Relationships(subreddit, WikiBannedRelationship, params)
+}
+
+// WikiContributors returns an iterator over the approved wiki contributors
+// of a subreddit.
+func (s *OAuthSession) WikiContributors(subreddit string, params ListingOptions) *RelationshipIterator {
+	return s.Relationships(subreddit, WikiContributorRelationship, params)
+}
+
+// FriendOptions holds the optional parameters of a Friend call.
+type FriendOptions struct {
+	// Duration is the length of a ban in days; 0 means permanent.
+	Duration int `url:"duration,omitempty"`
+	// BanReason is the short reason shown in the subreddit's ban list.
+	BanReason string `url:"ban_reason,omitempty"`
+	// BanMessage is sent to the banned user.
+	BanMessage string `url:"ban_message,omitempty"`
+	// BanContext is the fullname of the thing that prompted the ban.
+	BanContext string `url:"ban_context,omitempty"`
+	// Note is a moderator-only note attached to the relationship.
+	Note string `url:"note,omitempty"`
+	// Permissions applies to moderators, e.g. "+all" or "-all,+posts,+wiki".
+	Permissions string `url:"permissions,omitempty"`
+}
+
+// Friend creates a relationship between a user and a subreddit, e.g. bans,
+// mutes, approves or invites them as a moderator. If subreddit is empty the
+// relationship is created with the logged-in user instead.
+func (s *OAuthSession) Friend(subreddit, username string, rel relationshipType, opts FriendOptions) error {
+	return friend(s, subreddit, username, rel, opts)
+}
+
+// Unfriend removes a relationship between a user and a subreddit, e.g. unbans
+// or unmutes them. If subreddit is empty the relationship is removed from the
+// logged-in user instead.
+func (s *OAuthSession) Unfriend(subreddit, username string, rel relationshipType) error {
+	return unfriend(s, subreddit, username, rel)
+}
+
+// Friend creates a relationship between a user and a subreddit, see
+// OAuthSession.Friend.
+func (s LoginSession) Friend(subreddit, username string, rel relationshipType, opts FriendOptions) error {
+	return friend(s, subreddit, username, rel, opts)
+}
+
+// Unfriend removes a relationship between a user and a subreddit, see
+// OAuthSession.Unfriend.
+func (s LoginSession) Unfriend(subreddit, username string, rel relationshipType) error {
+	return unfriend(s, subreddit, username, rel)
+}
+
+func friend(c Client, subreddit, username string, rel relationshipType, opts FriendOptions) error {
+	if err := requireScope(c, relationshipScope(rel)); err != nil {
+		return err
+	}
+	v, err := query.Values(opts)
+	if err != nil {
+		return err
+	}
+	v.Set("name", username)
+	v.Set("type", string(rel))
+
+	if subreddit == "" && rel == FriendRelationship {
+		if o := oauthClient(c); o != nil {
+			return meFriend(o, username, opts.Note)
+		}
+	}
+	if subreddit == "" {
+		_, err = apiPost(c, &v, "/api/friend")
+	} else {
+		_, err = apiPost(c, &v, "/r/%s/api/friend", subreddit)
+	}
+	return err
+}
+
+func unfriend(c Client, subreddit, username string, rel relationshipType) error {
+	if err := requireScope(c, relationshipScope(rel)); err != nil {
+		return err
+	}
+	v := &url.Values{
+		"name": {username},
+		"type": {string(rel)},
+	}
+
+	if subreddit == "" && rel == FriendRelationship {
+		if o := oauthClient(c); o != nil {
+			_, err := o.Del(nil, "/api/v1/me/friends/%s", username)
+			return err
+		}
+	}
+
+	var err error
+	if subreddit == "" {
+		_, err = apiPost(c, v, "/api/unfriend")
+	} else {
+		_, err = apiPost(c, v, "/r/%s/api/unfriend", subreddit)
+	}
+	return err
+}
+
+// meFriend befriends a user through the OAuth API, which does not accept
+// FriendRelationship on /api/friend.
+func meFriend(s *OAuthSession, username, note string) error {
+	req := map[string]string{"name": username}
+	if note != "" {
+		req["note"] = note
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = s.Put(&url.Values{"json": {string(b)}}, "/api/v1/me/friends/%s", username)
+	return err
+}
+
+// oauthClient returns the OAuthSession requests through c are made with,
+// or nil if c is not backed by one.
+func oauthClient(c Client) *OAuthSession {
+	switch s := c.(type) {
+	case *OAuthSession:
+		return s
+	case LoginSession:
+		return s.oauth
+	case *LoginSession:
+		return s.oauth
+	}
+	return nil
+}
@@ -6,20 +6,42 @@ package geddit
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
 const (
-	BASE_URL = "http://www.reddit.com"
+	// BASE_URL is the default WWW endpoint, see Endpoints.
+	BASE_URL = "https://www.reddit.com"
 )
 
-func rurl(format string, args ...interface{}) string {
-	return fmt.Sprintf(BASE_URL+format, args...)
+// StatusError is returned when reddit answers a request with an HTTP status
+// other than the one expected. Body holds the response body, which for many
+// endpoints explains what went wrong.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
+// newStatusError builds a StatusError out of an unexpected response.
+func newStatusError(resp *http.Response) *StatusError {
+	body, _ := ioutil.ReadAll(resp.Body)
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       body,
+	}
 }
 
 type request struct {
@@ -27,9 +49,56 @@ type request struct {
 	values    *url.Values
 	cookie    *http.Cookie
 	useragent string
+	log       *requestLogger
+	doer      Doer
+	flights   *flightGroup
 }
 
 func (r request) getResponse() (*bytes.Buffer, error) {
+	req, err := r.newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	// Concurrent identical GETs share one request when coalescing is on.
+	if req.Method == "GET" {
+		return r.flights.do(req.URL.String(), func() ([]byte, error) {
+			return r.fetch(req)
+		})
+	}
+	body, err := r.fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(body), nil
+}
+
+// decode sends the request and decodes the JSON response into v as it is
+// read, instead of buffering it first like getResponse. Only responses
+// shared with coalesced requests or logged are read whole first.
+func (r request) decode(v interface{}) error {
+	req, err := r.newRequest()
+	if err != nil {
+		return err
+	}
+	if (r.flights != nil && req.Method == "GET") || r.log.dumpsBodies() {
+		body, err := r.getResponse()
+		if err != nil {
+			return err
+		}
+		return json.NewDecoder(body).Decode(v)
+	}
+
+	resp, err := r.send(req)
+	if err != nil {
+		return err
+	}
+	return decodeBody(resp, v)
+}
+
+// newRequest builds the HTTP request: a POST if there are values, a GET
+// otherwise.
+func (r request) newRequest() (*http.Request, error) {
 	// Determine the HTTP action.
 	var action, finalurl string
 	if r.values == nil {
@@ -49,25 +118,97 @@ func (r request) getResponse() (*bytes.Buffer, error) {
 		req.AddCookie(r.cookie)
 	}
 	req.Header.Set("User-Agent", r.useragent)
+	return req, nil
+}
+
+// send sends req and checks the response status. The caller must close the
+// response body.
+func (r request) send(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := r.doer.Do(req)
+	if err != nil {
+		r.log.logError(req.Method, req.URL.String(), err, start)
+		return nil, err
+	}
+	r.log.logResponse(req.Method, req.URL.String(), resp, start)
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newStatusError(resp)
+	}
+	return resp, nil
+}
 
-	cl := &http.Client{
-		Timeout: time.Second * 30,
+// fetch sends req and returns the response body.
+func (r request) fetch(req *http.Request) ([]byte, error) {
+	resp, err := r.send(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// Handle the request
-	resp, err := cl.Do(req)
+	respbytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	r.log.logBody(req.URL.String(), respbytes)
+
+	return respbytes, nil
+}
+
+// decodeBody decodes the JSON body of resp into v and closes it. The rest
+// of the body is drained so the connection can be reused.
+func decodeBody(resp *http.Response, v interface{}) error {
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(resp.Status)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return err
 	}
+	_, err := io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
 
-	respbytes, err := ioutil.ReadAll(resp.Body)
+// apiPost posts to an endpoint that understands api_type=json and unwraps
+// reddit's {"json": {"errors": [...], "data": {...}}} envelope, returning
+// the errors as a Go error and the raw data otherwise.
+func apiPost(c Client, params *url.Values, urlformat string, urlvars ...interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = &url.Values{}
+	}
+	params.Set("api_type", "json")
+	body, err := c.Post(params, urlformat, urlvars...)
 	if err != nil {
 		return nil, err
 	}
 
-	return bytes.NewBuffer(respbytes), nil
+	type Response struct {
+		JSON struct {
+			Errors [][]string      `json:"errors"`
+			Data   json.RawMessage `json:"data"`
+		} `json:"json"`
+	}
+	r := &Response{}
+	if body.Len() > 0 {
+		if err = json.NewDecoder(body).Decode(r); err != nil {
+			return nil, err
+		}
+	}
+	if err = jsonErrors(r.JSON.Errors); err != nil {
+		return nil, err
+	}
+	return r.JSON.Data, nil
+}
+
+// jsonErrors turns the errors array of a reddit JSON response into an error.
+func jsonErrors(errs [][]string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	var msg []string
+	for _, k := range errs {
+		if len(k) > 1 {
+			msg = append(msg, k[1])
+		} else if len(k) == 1 {
+			msg = append(msg, k[0])
+		}
+	}
+	return errors.New(strings.Join(msg, ", "))
 }
@@ -0,0 +1,125 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The OAuth scopes a reddit app can be granted.
+const (
+	AccountScope          = "account"
+	CredditsScope         = "creddits"
+	EditScope             = "edit"
+	FlairScope            = "flair"
+	HistoryScope          = "history"
+	IdentityScope         = "identity"
+	LiveManageScope       = "livemanage"
+	ModConfigScope        = "modconfig"
+	ModContributorsScope  = "modcontributors"
+	ModFlairScope         = "modflair"
+	ModLogScope           = "modlog"
+	ModMailScope          = "modmail"
+	ModOthersScope        = "modothers"
+	ModPostsScope         = "modposts"
+	ModSelfScope          = "modself"
+	ModWikiScope          = "modwiki"
+	MySubredditsScope     = "mysubreddits"
+	PrivateMessagesScope  = "privatemessages"
+	ReadScope             = "read"
+	ReportScope           = "report"
+	SaveScope             = "save"
+	StructuredStylesScope = "structuredstyles"
+	SubmitScope           = "submit"
+	SubscribeScope        = "subscribe"
+	VoteScope             = "vote"
+	WikiEditScope         = "wikiedit"
+	WikiReadScope         = "wikiread"
+)
+
+// MissingScopeError is returned, before any request is made, when a method
+// needs an OAuth scope the session was not granted.
+type MissingScopeError struct {
+	Scope string
+}
+
+func (e *MissingScopeError) Error() string {
+	return fmt.Sprintf("oauth session was not granted the %q scope", e.Scope)
+}
+
+// parseScopes parses the scope string returned with an access token.
+func parseScopes(scope string) map[string]bool {
+	scopes := make(map[string]bool)
+	for _, sc := range strings.FieldsFunc(scope, func(r rune) bool {
+		return r == ' ' || r == ','
+	}) {
+		scopes[sc] = true
+	}
+	return scopes
+}
+
+// HasScope reports whether the session was granted the given scope.
+func (s *OAuthSession) HasScope(scope string) bool {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	return s.scopes["*"] || s.scopes[scope]
+}
+
+// Scopes returns the scopes the session was granted, sorted.
+func (s *OAuthSession) Scopes() []string {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	return sortedScopes(s.scopes)
+}
+
+// RequiredScopes returns the scopes needed by the methods called on the
+// session so far, sorted. It is meant to help figure out which scopes an app
+// has to request.
+func (s *OAuthSession) RequiredScopes() []string {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	return sortedScopes(s.usedScopes)
+}
+
+// requireScope records that scope is needed and returns a
+// *MissingScopeError if the session was not granted it. An empty scope
+// means the caller does not need any particular scope.
+func (s *OAuthSession) requireScope(scope string) error {
+	if scope == "" {
+		return nil
+	}
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	if s.usedScopes == nil {
+		s.usedScopes = make(map[string]bool)
+	}
+	s.usedScopes[scope] = true
+	if s.scopes == nil || s.scopes["*"] || s.scopes[scope] {
+		return nil
+	}
+	return &MissingScopeError{Scope: scope}
+}
+
+// requireScope checks scope against c if it is scope-aware, so that models
+// bound to an OAuthSession fail fast like the session's own methods.
+func requireScope(c Client, scope string) error {
+	if sc, ok := c.(interface {
+		requireScope(string) error
+	}); ok {
+		return sc.requireScope(scope)
+	}
+	return nil
+}
+
+func sortedScopes(set map[string]bool) []string {
+	scopes := make([]string, 0, len(set))
+	for sc := range set {
+		scopes = append(scopes, sc)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
@@ -0,0 +1,66 @@
+package geddit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestMissingScope(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	srv.SetScopes(IdentityScope, ReadScope)
+	s, err := NewOAuthSession(geddittest.Moderator, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := s.Scopes(), []string{IdentityScope, ReadScope}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got scopes %v, want %v", got, want)
+	}
+	if !s.HasScope(ReadScope) || s.HasScope(ModLogScope) {
+		t.Errorf("HasScope disagrees with scopes %v", s.Scopes())
+	}
+	if _, err := s.Me(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing scopes fail before any request is sent.
+	sent := srv.Requests()
+	it := s.ModLog("golang", "", ListingOptions{})
+	if it.Next() {
+		t.Error("got a mod log entry without the modlog scope")
+	}
+	if err, ok := it.Err().(*MissingScopeError); !ok || err.Scope != ModLogScope {
+		t.Errorf("got %v from ModLog, want a *MissingScopeError for %q", it.Err(), ModLogScope)
+	}
+	if err, ok := s.Approve("t3_l1").(*MissingScopeError); !ok || err.Scope != ModPostsScope {
+		t.Errorf("got %v from Approve, want a *MissingScopeError for %q", err, ModPostsScope)
+	}
+	if n := srv.Requests() - sent; n != 0 {
+		t.Errorf("sent %d requests without the needed scopes", n)
+	}
+
+	want := []string{IdentityScope, ModLogScope, ModPostsScope}
+	if got := s.RequiredScopes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got required scopes %v, want %v", got, want)
+	}
+}
+
+func TestAllScopes(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.HasScope(ModLogScope) || !s.HasScope(IdentityScope) {
+		t.Errorf("a session granted %v lacks scopes", s.Scopes())
+	}
+	if err := s.requireScope(WikiEditScope); err != nil {
+		t.Error(err)
+	}
+}
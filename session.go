@@ -5,10 +5,12 @@
 package geddit
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"image"
 	"image/png"
+	"net/url"
 
 	"github.com/google/go-querystring/query"
 )
@@ -16,7 +18,13 @@ import (
 // Session represents an HTTP session with reddit.com
 // without logging into an account.
 type Session struct {
-	useragent string
+	useragent  string
+	log        *requestLogger
+	client     Doer
+	middleware []Middleware
+	cache      *responseCache
+	flights    *flightGroup
+	endpoints  Endpoints
 }
 
 // NewSession creates a new unauthenticated session to reddit.com.
@@ -38,24 +46,22 @@ func (s Session) SubredditSubmissions(subreddit string, sort popularitySort, par
 		return nil, err
 	}
 
-	baseUrl := BASE_URL
+	baseUrl := s.Endpoints().WWW
 
 	// If subbreddit given, add to URL
 	if subreddit != "" {
 		baseUrl += "/r/" + subreddit
 	}
 
-	redditUrl := fmt.Sprintf(baseUrl+"/%s.json?%s", sort, v.Encode())
+	redditUrl := baseUrl + fmt.Sprintf("/%s.json?%s", sort, v.Encode())
 
 	req := request{
 		url:       redditUrl,
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
-	body, err := req.getResponse()
-	if err != nil {
-		return nil, err
-	}
-
 	type Response struct {
 		Data struct {
 			Children []struct {
@@ -65,26 +71,71 @@ func (s Session) SubredditSubmissions(subreddit string, sort popularitySort, par
 	}
 
 	r := new(Response)
-	err = json.NewDecoder(body).Decode(r)
-	if err != nil {
+	if err = req.decode(r); err != nil {
 		return nil, err
 	}
 
 	submissions := make([]*Submission, len(r.Data.Children))
+	web := s.Endpoints().WWW
 	for i, child := range r.Data.Children {
+		child.Data.web = web
 		submissions[i] = child.Data
 	}
 
 	return submissions, nil
 }
 
-// AboutRedditor returns a Redditor for the given username.
-func (s Session) AboutRedditor(username string) (*Redditor, error) {
+// Get fetches the JSON version of a reddit.com page. Together with Post it
+// makes Session a Client.
+func (s Session) Get(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
 	req := &request{
-		url:       rurl("/user/%s/about.json", username),
+		url:       s.endpoints.jsonURL(params, urlformat, urlvars...),
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
-	body, err := req.getResponse()
+	return req.getResponse()
+}
+
+// getJSON fetches the JSON version of a reddit.com page and decodes it into
+// v as it is read.
+func (s Session) getJSON(v interface{}, params *url.Values, urlformat string, urlvars ...interface{}) error {
+	req := &request{
+		url:       s.endpoints.jsonURL(params, urlformat, urlvars...),
+		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
+	}
+	return req.decode(v)
+}
+
+// Post posts params to a reddit.com API endpoint without authentication.
+func (s Session) Post(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error) {
+	if params == nil {
+		params = &url.Values{}
+	}
+	req := &request{
+		url:       s.endpoints.rurl(urlformat, urlvars...),
+		values:    params,
+		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
+	}
+	return req.getResponse()
+}
+
+// AboutRedditor returns a Redditor for the given username.
+func (s Session) AboutRedditor(username string) (*Redditor, error) {
+	return aboutRedditor(s, username)
+}
+
+// aboutRedditor fetches a user's profile through c and binds the resulting
+// Redditor to it.
+func aboutRedditor(c Client, username string) (*Redditor, error) {
+	body, err := c.Get(nil, "/user/%s/about", username)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +148,7 @@ func (s Session) AboutRedditor(username string) (*Redditor, error) {
 	if err != nil {
 		return nil, err
 	}
+	r.Data.client = c
 
 	return &r.Data, nil
 }
@@ -104,8 +156,11 @@ func (s Session) AboutRedditor(username string) (*Redditor, error) {
 // AboutSubreddit returns a subreddit for the given subreddit name.
 func (s Session) AboutSubreddit(subreddit string) (*Subreddit, error) {
 	req := &request{
-		url:       rurl("/r/%s/about.json", subreddit),
+		url:       s.endpoints.rurl("/r/%s/about.json", subreddit),
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 	body, err := req.getResponse()
 	if err != nil {
@@ -121,6 +176,7 @@ func (s Session) AboutSubreddit(subreddit string) (*Subreddit, error) {
 	if err != nil {
 		return nil, err
 	}
+	r.Data.web = s.Endpoints().WWW
 
 	return &r.Data, nil
 }
@@ -128,30 +184,27 @@ func (s Session) AboutSubreddit(subreddit string) (*Subreddit, error) {
 // Comments returns the comments for a given Submission.
 func (s Session) Comments(h *Submission) ([]*Comment, error) {
 	req := &request{
-		url:       rurl("/comments/%s/.json", h.ID),
+		url:       s.endpoints.rurl("/comments/%s/.json", h.ID),
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
-	body, err := req.getResponse()
-	if err != nil {
-		return nil, err
-	}
-
-	r := json.NewDecoder(body)
-	var interf interface{}
-	if err = r.Decode(&interf); err != nil {
+	var page commentsPage
+	if err := req.decode(&page); err != nil {
 		return nil, err
 	}
-	helper := new(helper)
-	helper.buildComments(interf)
-
-	return helper.comments, nil
+	return page.comments(s.Endpoints().WWW), nil
 }
 
 // CaptchaImage gets the png corresponding to the captcha iden and decodes it
 func (s Session) CaptchaImage(iden string) (image.Image, error) {
 	req := &request{
-		url:       rurl("/captcha/%s", iden),
+		url:       s.endpoints.rurl("/captcha/%s", iden),
 		useragent: s.useragent,
+		log:       s.log,
+		doer:      s.doer(),
+		flights:   s.flights,
 	}
 
 	p, err := req.getResponse()
@@ -33,15 +33,19 @@ type Submission struct {
 	IsSaved      bool    `json:"saved"`
 	IsSticky     bool    `json:"stickied"`
 	BannedBy     *string `json:"banned_by"`
+
+	// web is the WWW host of the session the submission was fetched with.
+	web string
 }
 
 func (h Submission) voteID() string   { return h.FullID }
 func (h Submission) deleteID() string { return h.FullID }
 func (h Submission) replyID() string  { return h.FullID }
 
-// FullPermalink returns the full URL of a submission.
+// FullPermalink returns the full URL of a submission, on the WWW host of
+// the session it was fetched with.
 func (h *Submission) FullPermalink() string {
-	return BASE_URL + h.Permalink
+	return webURL(h.web, h.Permalink)
 }
 
 // String returns the string representation of a submission.
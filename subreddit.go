@@ -21,6 +21,19 @@ type Subreddit struct {
 	DateCreated float32 `json:"created_utc"`
 	NumSubs     int     `json:"subscribers"`
 	IsNSFW      bool    `json:"over18"`
+
+	// web is the WWW host of the session the subreddit was fetched with.
+	web string
+}
+
+// FullURL returns the full URL of a subreddit, on the WWW host of the
+// session it was fetched with.
+func (s *Subreddit) FullURL() string {
+	path := s.URL
+	if path == "" {
+		path = "/r/" + s.Name + "/"
+	}
+	return webURL(s.web, path)
 }
 
 // String returns the string representation of a subreddit.
@@ -0,0 +1,147 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Token holds the credentials of a session, so that it can be saved and
+// resumed later without logging in again. OAuthSession tokens set the
+// access token fields; LoginSession tokens set Cookie and Modhash.
+type Token struct {
+	AccessToken  string    `json:"access_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	Cookie       string    `json:"cookie,omitempty"`
+	Modhash      string    `json:"modhash,omitempty"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// expiryDelta is how long before its expiry a token is renewed, so that it
+// does not expire in flight.
+const expiryDelta = 30 * time.Second
+
+// Expired reports whether the token has expired or is about to. Tokens
+// with a zero Expiry never expire.
+func (t *Token) Expired() bool {
+	return !t.Expiry.IsZero() && time.Now().Add(expiryDelta).After(t.Expiry)
+}
+
+// ErrNoToken is returned by TokenStore.Load when no token is saved under
+// the key.
+var ErrNoToken = errors.New("no token stored")
+
+// TokenStore saves session tokens under a key, typically the username, see
+// WithTokenStore and ResumeLoginSession. Implementations must be safe for
+// concurrent use.
+type TokenStore interface {
+	Load(key string) (*Token, error)
+	Save(key string, t *Token) error
+	Delete(key string) error
+}
+
+// MemoryTokenStore is a TokenStore keeping tokens in memory, which lets
+// sessions of the same process share them.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+// NewMemoryTokenStore creates an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]Token)}
+}
+
+// Load implements TokenStore.
+func (m *MemoryTokenStore) Load(key string) (*Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[key]
+	if !ok {
+		return nil, ErrNoToken
+	}
+	return &t, nil
+}
+
+// Save implements TokenStore.
+func (m *MemoryTokenStore) Save(key string, t *Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[key] = *t
+	return nil
+}
+
+// Delete implements TokenStore.
+func (m *MemoryTokenStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tokens, key)
+	return nil
+}
+
+// FileTokenStore is a TokenStore storing each token as a JSON file, readable
+// only by the current user, in a directory.
+type FileTokenStore struct {
+	dir string
+}
+
+// NewFileTokenStore creates a FileTokenStore in dir, creating the directory
+// if needed.
+func NewFileTokenStore(dir string) (*FileTokenStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileTokenStore{dir: dir}, nil
+}
+
+func (f *FileTokenStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, "token-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load implements TokenStore.
+func (f *FileTokenStore) Load(key string) (*Token, error) {
+	b, err := ioutil.ReadFile(f.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNoToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &Token{}
+	if err = json.Unmarshal(b, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Save implements TokenStore. The file is replaced atomically, so
+// processes sharing the directory never read a partial token.
+func (f *FileTokenStore) Save(key string, t *Token) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path(key), b)
+}
+
+// Delete implements TokenStore.
+func (f *FileTokenStore) Delete(key string) error {
+	err := os.Remove(f.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,236 @@
+package geddit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+// grantRecorder records the grant type of the access token requests, and
+// counts the cookie logins, going through it.
+type grantRecorder struct {
+	mu     sync.Mutex
+	grants []string
+	logins int
+}
+
+func (g *grantRecorder) middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		var grant string
+		if req.URL.Path == "/api/v1/access_token" && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			b, _ := ioutil.ReadAll(body)
+			form, _ := url.ParseQuery(string(b))
+			grant = form.Get("grant_type")
+		}
+		g.mu.Lock()
+		if grant != "" {
+			g.grants = append(g.grants, grant)
+		}
+		if strings.HasPrefix(req.URL.Path, "/api/login") {
+			g.logins++
+		}
+		g.mu.Unlock()
+		return next.Do(req)
+	})
+}
+
+func (g *grantRecorder) sent() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.grants...)
+}
+
+func TestTokenStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	files, err := NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]TokenStore{
+		"memory": NewMemoryTokenStore(),
+		"file":   files,
+	}
+	want := &Token{
+		AccessToken:  "access",
+		TokenType:    "bearer",
+		RefreshToken: "refresh",
+		Scope:        "identity read",
+		Expiry:       time.Now().Add(time.Hour).Round(0).UTC(),
+	}
+
+	for name, store := range stores {
+		if _, err := store.Load(geddittest.Username); err != ErrNoToken {
+			t.Errorf("%s: got %v loading a missing token, want ErrNoToken", name, err)
+		}
+		if err := store.Save(geddittest.Username, want); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := store.Load(geddittest.Username)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+		if _, err := store.Load(geddittest.Moderator); err != ErrNoToken {
+			t.Errorf("%s: got %v loading the token of another key, want ErrNoToken", name, err)
+		}
+		if err := store.Delete(geddittest.Username); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := store.Load(geddittest.Username); err != ErrNoToken {
+			t.Errorf("%s: got %v loading a deleted token, want ErrNoToken", name, err)
+		}
+		if err := store.Delete(geddittest.Username); err != nil {
+			t.Errorf("%s: got %v deleting a missing token", name, err)
+		}
+	}
+
+	// Another process sharing the directory.
+	if err := files.Save(geddittest.Username, want); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := other.Load(geddittest.Username); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, %v from another FileTokenStore, want %+v", got, err, want)
+	}
+}
+
+func TestOAuthSessionResumesFromTokenStore(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	store := NewMemoryTokenStore()
+	rec := &grantRecorder{}
+	newSession := func() *OAuthSession {
+		s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+			geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()),
+			WithMiddleware(rec.middleware), WithTokenStore(store, ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	first := newSession()
+	saved, err := store.Load(geddittest.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.AccessToken != first.Token().AccessToken {
+		t.Errorf("saved token %q, want %q", saved.AccessToken, first.Token().AccessToken)
+	}
+
+	second := newSession()
+	if _, err := second.Me(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.sent(); len(got) != 1 {
+		t.Errorf("got token requests %v, want a single one", got)
+	}
+	if second.Token().AccessToken != first.Token().AccessToken {
+		t.Error("resumed session does not use the stored token")
+	}
+}
+
+func TestOAuthSessionFallsBackToPasswordGrant(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	store := NewMemoryTokenStore()
+	store.Save(geddittest.Username, &Token{
+		AccessToken:  "expired",
+		TokenType:    "bearer",
+		RefreshToken: "revoked",
+		Scope:        "*",
+		Expiry:       time.Now().Add(-time.Hour),
+	})
+	rec := &grantRecorder{}
+
+	s, err := NewOAuthSession(geddittest.Username, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()),
+		WithMiddleware(rec.middleware), WithTokenStore(store, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Me(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"refresh_token", "password"}
+	if got := rec.sent(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got grants %v, want %v", got, want)
+	}
+	saved, err := store.Load(geddittest.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.AccessToken != s.Token().AccessToken || saved.Expired() {
+		t.Errorf("stored token %+v was not replaced", saved)
+	}
+
+	// Without a password there is nothing to fall back to.
+	store.Save(geddittest.Username, &Token{RefreshToken: "revoked", Expiry: time.Now().Add(-time.Hour)})
+	_, err = NewOAuthSession(geddittest.Username, "", "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()),
+		WithTokenStore(store, ""))
+	if err == nil {
+		t.Error("got a session from a revoked refresh token and no password")
+	}
+}
+
+func TestResumeLoginSession(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	rec := &grantRecorder{}
+	setDefaultClient(t, rec.middleware(srv.Client()))
+	store, err := NewFileTokenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := ResumeLoginSession(geddittest.Username, geddittest.Password, "geddit tests", store, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ResumeLoginSession(geddittest.Username, geddittest.Password, "geddit tests", store, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.logins != 1 {
+		t.Errorf("logged in %d times, want once", rec.logins)
+	}
+	if !reflect.DeepEqual(second.Token(), first.Token()) {
+		t.Errorf("resumed session has token %+v, want %+v", second.Token(), first.Token())
+	}
+	me, err := second.Me()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Name != geddittest.Username {
+		t.Errorf("resumed session is logged in as %q", me.Name)
+	}
+
+	// An expired cookie is replaced by logging in again.
+	store.Save(geddittest.Username, &Token{Cookie: "stale", Expiry: time.Now().Add(-time.Hour)})
+	if _, err := ResumeLoginSession(geddittest.Username, geddittest.Password, "geddit tests", store, ""); err != nil {
+		t.Fatal(err)
+	}
+	if rec.logins != 2 {
+		t.Errorf("logged in %d times, want twice", rec.logins)
+	}
+	if saved, _ := store.Load(geddittest.Username); saved.Cookie == "stale" {
+		t.Error("expired cookie was not replaced")
+	}
+}
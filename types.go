@@ -4,6 +4,11 @@
 
 package geddit
 
+import (
+	"bytes"
+	"net/url"
+)
+
 // vote represents the three possible states of a vote on reddit.
 type vote string
 
@@ -64,6 +69,15 @@ const (
 	AllTime            = "all"
 )
 
+// savedType restricts a saved listing to submissions or comments.
+type savedType string
+
+const (
+	SavedAll      savedType = ""
+	SavedLinks    savedType = "links"
+	SavedComments savedType = "comments"
+)
+
 type ListingOptions struct {
 	Time    string `url:"t,omitempty"`
 	Limit   int    `url:"limit,omitempty"`
@@ -79,6 +93,14 @@ type Param struct {
 	Value string
 }
 
+// Client represents a session able to perform requests against reddit.com.
+// Session, LoginSession and OAuthSession all implement it, which lets
+// models such as Redditor work regardless of how they were obtained.
+type Client interface {
+	Get(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error)
+	Post(params *url.Values, urlformat string, urlvars ...interface{}) (*bytes.Buffer, error)
+}
+
 // Voter represents something that can be voted on reddit.com.
 type Voter interface {
 	voteID() string
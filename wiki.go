@@ -0,0 +1,319 @@
+// Copyright 2012 Jimmy Zelinskie. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geddit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// WikiPage represents a revision of a subreddit wiki page.
+type WikiPage struct {
+	Name         string    `json:"-"`
+	Subreddit    string    `json:"-"`
+	Content      string    `json:"content_md"`
+	ContentHTML  string    `json:"content_html"`
+	MayRevise    bool      `json:"may_revise"`
+	Reason       *string   `json:"reason"`
+	RevisionID   string    `json:"revision_id"`
+	RevisionDate float64   `json:"revision_date"`
+	RevisionBy   *Redditor `json:"-"`
+}
+
+// UnmarshalJSON decodes a wiki page, unwrapping the author of the revision.
+func (p *WikiPage) UnmarshalJSON(b []byte) error {
+	type wikiPage WikiPage
+	aux := struct {
+		*wikiPage
+		RevisionBy *struct {
+			Data Redditor `json:"data"`
+		} `json:"revision_by"`
+	}{wikiPage: (*wikiPage)(p)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if aux.RevisionBy != nil {
+		p.RevisionBy = &aux.RevisionBy.Data
+	}
+	return nil
+}
+
+// String returns the string representation of a wiki page.
+func (p *WikiPage) String() string {
+	return fmt.Sprintf("/r/%s/wiki/%s (%s)", p.Subreddit, p.Name, p.RevisionID)
+}
+
+// WikiRevision represents an entry of a wiki page's revision history.
+type WikiRevision struct {
+	ID        string    `json:"id"`
+	Page      string    `json:"page"`
+	Reason    *string   `json:"reason"`
+	Timestamp float64   `json:"timestamp"`
+	Hidden    bool      `json:"revision_hidden"`
+	Author    *Redditor `json:"-"`
+}
+
+// UnmarshalJSON decodes a wiki revision, unwrapping its author.
+func (r *WikiRevision) UnmarshalJSON(b []byte) error {
+	type wikiRevision WikiRevision
+	aux := struct {
+		*wikiRevision
+		Author *struct {
+			Data Redditor `json:"data"`
+		} `json:"author"`
+	}{wikiRevision: (*wikiRevision)(r)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if aux.Author != nil {
+		r.Author = &aux.Author.Data
+	}
+	return nil
+}
+
+// WikiSettings holds the settings of a wiki page.
+type WikiSettings struct {
+	// PermLevel is 0 to use the subreddit's wiki permissions, 1 for approved
+	// editors only and 2 for moderators only.
+	PermLevel int         `json:"permlevel"`
+	Listed    bool        `json:"listed"`
+	Editors   []*Redditor `json:"-"`
+}
+
+// UnmarshalJSON decodes wiki settings, unwrapping the allowed editors.
+func (w *WikiSettings) UnmarshalJSON(b []byte) error {
+	type wikiSettings WikiSettings
+	aux := struct {
+		*wikiSettings
+		Editors []struct {
+			Data Redditor `json:"data"`
+		} `json:"editors"`
+	}{wikiSettings: (*wikiSettings)(w)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	w.Editors = make([]*Redditor, len(aux.Editors))
+	for i := range aux.Editors {
+		w.Editors[i] = &aux.Editors[i].Data
+	}
+	return nil
+}
+
+// WikiConflictError is returned by EditWikiPage when the page was revised
+// by someone else since the previous revision given.
+type WikiConflictError struct {
+	// NewRevision is the ID of the revision the edit raced with.
+	NewRevision string `json:"newrevision"`
+	// NewContent is the content of that revision.
+	NewContent string `json:"newcontent"`
+	// DiffContent is an HTML diff between NewContent and the rejected edit.
+	DiffContent string `json:"diffcontent"`
+}
+
+func (e *WikiConflictError) Error() string {
+	return fmt.Sprintf("wiki edit conflicts with revision %s", e.NewRevision)
+}
+
+// WikiRevisionIterator iterates over the revisions of a wiki, in the same
+// fashion as RelationshipIterator.
+type WikiRevisionIterator struct {
+	iter *listingIterator
+	cur  *WikiRevision
+	err  error
+}
+
+// Next advances the iterator to the next revision. It returns false when
+// there are no more revisions or an error occurred.
+func (it *WikiRevisionIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	t, ok := it.iter.next()
+	if !ok {
+		it.err = it.iter.err
+		return false
+	}
+	r := &WikiRevision{}
+	if it.err = json.Unmarshal(t.Data, r); it.err != nil {
+		return false
+	}
+	it.cur = r
+	return true
+}
+
+// Revision returns the revision the iterator currently points to.
+func (it *WikiRevisionIterator) Revision() *WikiRevision {
+	return it.cur
+}
+
+// After returns the value to pass as ListingOptions.After to resume the
+// listing after the last page fetched.
+func (it *WikiRevisionIterator) After() string {
+	return it.iter.after
+}
+
+// Err returns the first error encountered by the iterator.
+func (it *WikiRevisionIterator) Err() error {
+	return it.err
+}
+
+// WikiPages returns the names of the wiki pages of a subreddit.
+func (s *OAuthSession) WikiPages(subreddit string) ([]string, error) {
+	if err := s.requireScope(WikiReadScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/r/%s/wiki/pages", subreddit)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data []string `json:"data"`
+	}
+	r := &Response{}
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+	return r.Data, nil
+}
+
+// WikiPage returns a wiki page of a subreddit. If revision is not empty that
+// revision of the page is returned instead of the current one.
+func (s *OAuthSession) WikiPage(subreddit, page, revision string) (*WikiPage, error) {
+	if err := s.requireScope(WikiReadScope); err != nil {
+		return nil, err
+	}
+	v := &url.Values{}
+	if revision != "" {
+		v.Set("v", revision)
+	}
+	body, err := s.Get(v, "/r/%s/wiki/%s", subreddit, page)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data WikiPage `json:"data"`
+	}
+	r := &Response{}
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+	r.Data.Name = page
+	r.Data.Subreddit = subreddit
+	return &r.Data, nil
+}
+
+// WikiRevisions returns an iterator over the revisions of a wiki page,
+// newest first. If page is empty the revisions of every page are returned.
+func (s *OAuthSession) WikiRevisions(subreddit, page string, params ListingOptions) *WikiRevisionIterator {
+	if err := s.requireScope(WikiReadScope); err != nil {
+		return &WikiRevisionIterator{err: err}
+	}
+	if page == "" {
+		return &WikiRevisionIterator{
+			iter: newListingIterator(s, params, nil, "/r/%s/wiki/revisions", subreddit),
+		}
+	}
+	return &WikiRevisionIterator{
+		iter: newListingIterator(s, params, nil, "/r/%s/wiki/revisions/%s", subreddit, page),
+	}
+}
+
+// EditWikiPage replaces the content of a wiki page, creating it if needed.
+// If previous is the ID of the revision the edit is based on and the page
+// was revised since, a *WikiConflictError is returned.
+func (s *OAuthSession) EditWikiPage(subreddit, page, content, reason, previous string) error {
+	if err := s.requireScope(WikiEditScope); err != nil {
+		return err
+	}
+	v := &url.Values{
+		"page":    {page},
+		"content": {content},
+	}
+	if reason != "" {
+		v.Set("reason", reason)
+	}
+	if previous != "" {
+		v.Set("previous", previous)
+	}
+
+	_, err := s.Post(v, "/r/%s/api/wiki/edit", subreddit)
+	if serr, ok := err.(*StatusError); ok && serr.StatusCode == http.StatusConflict {
+		conflict := &WikiConflictError{}
+		if json.Unmarshal(serr.Body, conflict) == nil {
+			return conflict
+		}
+	}
+	return err
+}
+
+// WikiSettings returns the settings of a wiki page.
+func (s *OAuthSession) WikiSettings(subreddit, page string) (*WikiSettings, error) {
+	if err := s.requireScope(ModWikiScope); err != nil {
+		return nil, err
+	}
+	body, err := s.Get(nil, "/r/%s/wiki/settings/%s", subreddit, page)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data WikiSettings `json:"data"`
+	}
+	r := &Response{}
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+	return &r.Data, nil
+}
+
+// SetWikiSettings updates the permission level and visibility of a wiki page.
+func (s *OAuthSession) SetWikiSettings(subreddit, page string, permlevel int, listed bool) (*WikiSettings, error) {
+	if err := s.requireScope(ModWikiScope); err != nil {
+		return nil, err
+	}
+	v := &url.Values{
+		"permlevel": {strconv.Itoa(permlevel)},
+		"listed":    {strconv.FormatBool(listed)},
+	}
+	body, err := s.Post(v, "/r/%s/wiki/settings/%s", subreddit, page)
+	if err != nil {
+		return nil, err
+	}
+
+	type Response struct {
+		Data WikiSettings `json:"data"`
+	}
+	r := &Response{}
+	if err = json.NewDecoder(body).Decode(r); err != nil {
+		return nil, err
+	}
+	return &r.Data, nil
+}
+
+// AddWikiEditor allows a user to edit a wiki page.
+func (s *OAuthSession) AddWikiEditor(subreddit, page, username string) error {
+	return s.wikiEditor(subreddit, page, username, "add")
+}
+
+// RemoveWikiEditor revokes a user's permission to edit a wiki page.
+func (s *OAuthSession) RemoveWikiEditor(subreddit, page, username string) error {
+	return s.wikiEditor(subreddit, page, username, "del")
+}
+
+func (s *OAuthSession) wikiEditor(subreddit, page, username, act string) error {
+	if err := s.requireScope(ModWikiScope); err != nil {
+		return err
+	}
+	_, err := s.Post(&url.Values{
+		"page":     {page},
+		"username": {username},
+	}, "/r/%s/api/wiki/alloweditor/%s", subreddit, act)
+	return err
+}
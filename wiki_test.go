@@ -0,0 +1,56 @@
+package geddit
+
+import (
+	"testing"
+
+	"github.com/jzelinskie/geddit/geddittest"
+)
+
+func TestEditWikiPageConflict(t *testing.T) {
+	srv := geddittest.NewServer(geddittest.DefaultFixtures())
+	defer srv.Close()
+	s, err := NewOAuthSession(geddittest.Moderator, geddittest.Password, "geddit tests",
+		geddittest.ClientID, geddittest.ClientSecret, WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.EditWikiPage("golang", "index", "Welcome.", "create", ""); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.WikiPage("golang", "index", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Content != "Welcome." || first.RevisionID == "" || first.RevisionBy == nil || first.RevisionBy.Name != geddittest.Moderator {
+		t.Fatalf("got page %+v", first)
+	}
+
+	if err := s.EditWikiPage("golang", "index", "Welcome, gophers.", "", first.RevisionID); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.WikiPage("golang", "index", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another edit based on the first revision races with the second.
+	err = s.EditWikiPage("golang", "index", "Hello.", "", first.RevisionID)
+	conflict, ok := err.(*WikiConflictError)
+	if !ok {
+		t.Fatalf("got %v, want a *WikiConflictError", err)
+	}
+	if conflict.NewRevision != second.RevisionID || conflict.NewContent != "Welcome, gophers." || conflict.DiffContent == "" {
+		t.Errorf("got conflict %+v, want revision %s", conflict, second.RevisionID)
+	}
+	if page, err := s.WikiPage("golang", "index", ""); err != nil || page.RevisionID != second.RevisionID {
+		t.Errorf("got %+v, %v after the rejected edit", page, err)
+	}
+
+	// Other errors are not conflicts.
+	if err := s.EditWikiPage("rust", "index", "Hello.", "", ""); err == nil {
+		t.Error("got no error editing the wiki of a missing subreddit")
+	} else if _, ok := err.(*StatusError); !ok {
+		t.Errorf("got %v, want a *StatusError", err)
+	}
+}